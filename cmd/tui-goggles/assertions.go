@@ -0,0 +1,271 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/your-username/tui-goggles/internal/terminal"
+)
+
+// Assertion kinds, as reported in JSON output.
+const (
+	assertContains    = "contains"
+	assertNotContains = "not_contains"
+	assertRegex       = "regex"
+	assertCount       = "count"
+	assertRegion      = "region"
+	assertHighlighted = "highlighted"
+)
+
+// assertion is a parsed -assert* flag.
+type assertion struct {
+	kind   string
+	text   string
+	re     *regexp.Regexp
+	count  int
+	region *regionMatch
+}
+
+// stepAssertion is an assertion attached to the capture taken after a
+// given key in -capture-each mode (step 0 is the initial screen).
+type stepAssertion struct {
+	step int
+	assertion
+}
+
+// AssertionResult reports the outcome of a single assertion.
+type AssertionResult struct {
+	Step     *int       `json:"step,omitempty"`
+	Frame    *int       `json:"frame,omitempty"`
+	Kind     string     `json:"kind"`
+	Text     string     `json:"text"`
+	Passed   bool       `json:"passed"`
+	Expected *int       `json:"expected,omitempty"`
+	Count    int        `json:"count"`
+	Matches  []Location `json:"matches,omitempty"`
+	Message  string     `json:"message,omitempty"`
+}
+
+// Location is a 0-indexed screen position.
+type Location struct {
+	Row int `json:"row"`
+	Col int `json:"col"`
+}
+
+// parseCountAssertion parses "text=N". The last '=' separates the count so
+// the text itself may contain '='.
+func parseCountAssertion(spec string) (assertion, error) {
+	i := strings.LastIndex(spec, "=")
+	if i <= 0 {
+		return assertion{}, fmt.Errorf("invalid count assertion %q: expected text=N", spec)
+	}
+	n, err := strconv.Atoi(spec[i+1:])
+	if err != nil || n < 0 {
+		return assertion{}, fmt.Errorf("invalid count assertion %q: %q is not a non-negative integer", spec, spec[i+1:])
+	}
+	return assertion{kind: assertCount, text: spec[:i], count: n}, nil
+}
+
+// parseRegexAssertion compiles a -assert-regex pattern.
+func parseRegexAssertion(pattern string) (assertion, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return assertion{}, fmt.Errorf("invalid regex %q: %w", pattern, err)
+	}
+	return assertion{kind: assertRegex, text: pattern, re: re}, nil
+}
+
+// parseStepAssertion parses "N:text" or "N:kind:text", where kind is one of
+// not, regex, count, at or highlighted.
+func parseStepAssertion(spec string) (stepAssertion, error) {
+	stepStr, rest, ok := strings.Cut(spec, ":")
+	step, err := strconv.Atoi(stepStr)
+	if !ok || err != nil || step < 0 || rest == "" {
+		return stepAssertion{}, fmt.Errorf("invalid step assertion %q: expected N:text or N:kind:text", spec)
+	}
+
+	kind, text, hasKind := strings.Cut(rest, ":")
+	var a assertion
+	switch {
+	case hasKind && kind == "not":
+		a = assertion{kind: assertNotContains, text: text}
+	case hasKind && kind == "regex":
+		a, err = parseRegexAssertion(text)
+	case hasKind && kind == "count":
+		a, err = parseCountAssertion(text)
+	case hasKind && kind == "at":
+		var m regionMatch
+		m, err = parseRegionMatch(text)
+		a = assertion{kind: assertRegion, text: m.text, region: &m}
+	case hasKind && kind == "highlighted":
+		a = assertion{kind: assertHighlighted, text: text}
+	default:
+		a = assertion{kind: assertContains, text: rest}
+	}
+	if err != nil {
+		return stepAssertion{}, fmt.Errorf("invalid step assertion %q: %w", spec, err)
+	}
+	if a.text == "" {
+		return stepAssertion{}, fmt.Errorf("invalid step assertion %q: empty text", spec)
+	}
+
+	return stepAssertion{step: step, assertion: a}, nil
+}
+
+// evaluate checks the assertion against a capture.
+func (a assertion) evaluate(result CaptureResult) AssertionResult {
+	res := AssertionResult{Kind: a.kind, Text: a.text}
+
+	switch a.kind {
+	case assertContains:
+		res.Matches = findAll(result.plainScreen, a.text)
+		res.Passed = len(res.Matches) > 0
+		if !res.Passed {
+			res.Message = fmt.Sprintf("text %q not found on screen", a.text)
+		}
+	case assertNotContains:
+		res.Matches = findAll(result.plainScreen, a.text)
+		res.Passed = len(res.Matches) == 0
+		if !res.Passed {
+			m := res.Matches[0]
+			res.Message = fmt.Sprintf("text %q found on screen at row %d, col %d", a.text, m.Row, m.Col)
+		}
+	case assertRegex:
+		for _, idx := range a.re.FindAllStringIndex(result.plainScreen, -1) {
+			res.Matches = append(res.Matches, offsetToLocation(result.plainScreen, idx[0]))
+		}
+		res.Passed = len(res.Matches) > 0
+		if !res.Passed {
+			res.Message = fmt.Sprintf("pattern %q did not match screen", a.text)
+		}
+	case assertCount:
+		res.Matches = findAll(result.plainScreen, a.text)
+		expected := a.count
+		res.Expected = &expected
+		res.Passed = len(res.Matches) == a.count
+		if !res.Passed {
+			res.Message = fmt.Sprintf("text %q found %d times, expected %d", a.text, len(res.Matches), a.count)
+		}
+	case assertRegion:
+		r := a.region.rect
+		region := result.snap.RegionText(r)
+		for _, m := range findAll(region, a.region.text) {
+			res.Matches = append(res.Matches, Location{Row: m.Row + r.Row, Col: m.Col + r.Col})
+		}
+		res.Passed = len(res.Matches) > 0
+		if !res.Passed {
+			res.Message = fmt.Sprintf("text %q not found in region row %d, col %d, width %d, height %d",
+				a.region.text, r.Row, r.Col, r.Width, r.Height)
+		}
+	case assertHighlighted:
+		res.Matches = findHighlighted(result.snap, a.text)
+		res.Passed = len(res.Matches) > 0
+		if !res.Passed {
+			res.Message = fmt.Sprintf("text %q not found in a highlighted (reverse-video) run", a.text)
+		}
+	}
+
+	res.Count = len(res.Matches)
+	return res
+}
+
+// evaluateStepAssertions runs the assertions attached to one capture step.
+func evaluateStepAssertions(asserts []stepAssertion, step int, result CaptureResult) (results []AssertionResult, passed bool) {
+	passed = true
+	for _, a := range asserts {
+		if a.step != step {
+			continue
+		}
+		r := a.evaluate(result)
+		s := step
+		r.Step = &s
+		if !r.Passed {
+			passed = false
+		}
+		results = append(results, r)
+	}
+	return results, passed
+}
+
+// missingStepResults reports step assertions whose step was never captured.
+func missingStepResults(asserts []stepAssertion, captured int) []AssertionResult {
+	var results []AssertionResult
+	for _, a := range asserts {
+		if a.step < captured {
+			continue
+		}
+		s := a.step
+		results = append(results, AssertionResult{
+			Step:    &s,
+			Kind:    a.kind,
+			Text:    a.text,
+			Message: fmt.Sprintf("no capture for step %d (only %d captures)", a.step, captured),
+		})
+	}
+	return results
+}
+
+// evaluateAssertions runs every assertion against a capture.
+func evaluateAssertions(asserts []assertion, result CaptureResult) (results []AssertionResult, passed bool) {
+	passed = true
+	for _, a := range asserts {
+		r := a.evaluate(result)
+		if !r.Passed {
+			passed = false
+		}
+		results = append(results, r)
+	}
+	return results, passed
+}
+
+// findHighlighted returns the positions of text that lies entirely within
+// a run of reverse-video cells.
+func findHighlighted(snap terminal.Snapshot, text string) []Location {
+	var locs []Location
+	for y, row := range snap.Cells {
+		for x := 0; x < len(row); {
+			if !row[x].Has(terminal.AttrReverse) {
+				x++
+				continue
+			}
+			start := x
+			for x < len(row) && row[x].Has(terminal.AttrReverse) {
+				x++
+			}
+			run := snap.RegionText(terminal.Rect{Row: y, Col: start, Width: x - start, Height: 1})
+			for _, m := range findAll(run, text) {
+				locs = append(locs, Location{Row: y, Col: start + m.Col})
+			}
+		}
+	}
+	return locs
+}
+
+// findAll returns the positions of every non-overlapping occurrence of text.
+func findAll(screen, text string) []Location {
+	if text == "" {
+		return nil
+	}
+	var locs []Location
+	offset := 0
+	for {
+		i := strings.Index(screen[offset:], text)
+		if i < 0 {
+			return locs
+		}
+		locs = append(locs, offsetToLocation(screen, offset+i))
+		offset += i + len(text)
+	}
+}
+
+// offsetToLocation converts a byte offset in a rendered screen into a row
+// and (rune) column.
+func offsetToLocation(screen string, offset int) Location {
+	before := screen[:offset]
+	row := strings.Count(before, "\n")
+	lineStart := strings.LastIndex(before, "\n") + 1
+	return Location{Row: row, Col: utf8.RuneCountInString(before[lineStart:])}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/your-username/tui-goggles/internal/terminal"
+)
+
+// testCapture builds a capture of a screen of the given lines, padded to
+// width cols. Cells of the lines in reverse, where it is not a space, are
+// drawn in reverse video.
+func testCapture(cols int, lines []string, reverse ...string) CaptureResult {
+	snap := terminal.Snapshot{Cols: cols, Rows: len(lines)}
+	for y, line := range lines {
+		runes := []rune(line)
+		var rev []rune
+		if y < len(reverse) {
+			rev = []rune(reverse[y])
+		}
+		row := make([]terminal.Cell, cols)
+		for x := range row {
+			row[x] = terminal.Cell{Char: ' ', FG: terminal.DefaultFG, BG: terminal.DefaultBG}
+			if x < len(runes) {
+				row[x].Char = runes[x]
+			}
+			if x < len(rev) && rev[x] != ' ' {
+				row[x].Attr |= terminal.AttrReverse
+			}
+		}
+		snap.Cells = append(snap.Cells, row)
+	}
+	screen := strings.TrimSuffix(snap.String(), "\n")
+	return CaptureResult{Screen: screen, plainScreen: screen, compareScreen: screen, snap: snap}
+}
+
+func TestParseCountAssertion(t *testing.T) {
+	tests := []struct {
+		spec      string
+		wantText  string
+		wantCount int
+		wantErr   bool
+	}{
+		{spec: "foo=2", wantText: "foo", wantCount: 2},
+		{spec: "a=b=0", wantText: "a=b", wantCount: 0},
+		{spec: "foo", wantErr: true},
+		{spec: "foo=", wantErr: true},
+		{spec: "=3", wantErr: true},
+		{spec: "foo=-1", wantErr: true},
+		{spec: "foo=two", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			a, err := parseCountAssertion(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseCountAssertion(%q) = %+v, want error", tt.spec, a)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if a.kind != assertCount || a.text != tt.wantText || a.count != tt.wantCount {
+				t.Errorf("parseCountAssertion(%q) = %+v, want text %q, count %d", tt.spec, a, tt.wantText, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestParseRegexAssertion(t *testing.T) {
+	a, err := parseRegexAssertion(`v\d+\.\d+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.kind != assertRegex || a.re == nil {
+		t.Errorf("parseRegexAssertion() = %+v", a)
+	}
+
+	for _, bad := range []string{"(", "[a-", `\p{Nope}`} {
+		if _, err := parseRegexAssertion(bad); err == nil {
+			t.Errorf("parseRegexAssertion(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestParseStepAssertion(t *testing.T) {
+	tests := []struct {
+		spec     string
+		wantStep int
+		wantKind string
+		wantText string
+		wantErr  bool
+	}{
+		{spec: "0:Welcome", wantStep: 0, wantKind: assertContains, wantText: "Welcome"},
+		{spec: "2:not:Error", wantStep: 2, wantKind: assertNotContains, wantText: "Error"},
+		{spec: "1:regex:^> ", wantStep: 1, wantKind: assertRegex, wantText: "^> "},
+		{spec: "1:count:item=3", wantStep: 1, wantKind: assertCount, wantText: "item"},
+		{spec: "3:at:0,0,10:Title", wantStep: 3, wantKind: assertRegion, wantText: "Title"},
+		{spec: "1:highlighted:Save", wantStep: 1, wantKind: assertHighlighted, wantText: "Save"},
+		// An unknown kind is part of the text
+		{spec: "1:Time: 12:00", wantStep: 1, wantKind: assertContains, wantText: "Time: 12:00"},
+
+		{spec: "Welcome", wantErr: true},
+		{spec: "x:Welcome", wantErr: true},
+		{spec: "-1:Welcome", wantErr: true},
+		{spec: "1:", wantErr: true},
+		{spec: "1:not:", wantErr: true},
+		{spec: "1:regex:(", wantErr: true},
+		{spec: "1:count:item", wantErr: true},
+		{spec: "1:count:item=", wantErr: true},
+		{spec: "1:at:0,0:", wantErr: true},
+		{spec: "1:at:x,0:Title", wantErr: true},
+		{spec: "1:highlighted:", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			a, err := parseStepAssertion(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseStepAssertion(%q) = %+v, want error", tt.spec, a)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if a.step != tt.wantStep || a.kind != tt.wantKind || a.text != tt.wantText {
+				t.Errorf("parseStepAssertion(%q) = step %d, kind %q, text %q; want %d, %q, %q",
+					tt.spec, a.step, a.kind, a.text, tt.wantStep, tt.wantKind, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestAssertionEvaluate(t *testing.T) {
+	capture := testCapture(20, []string{
+		"Files  Edit  View",
+		"> item one",
+		"  item two",
+		"Status: ready",
+	}, "", "  rrrrrrrr")
+
+	regex := func(pattern string) assertion {
+		a, err := parseRegexAssertion(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+	region := func(spec string) assertion {
+		m, err := parseRegionMatch(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return assertion{kind: assertRegion, text: m.text, region: &m}
+	}
+
+	tests := []struct {
+		name        string
+		assertion   assertion
+		wantPassed  bool
+		wantMatches []Location
+	}{
+		{"contains", assertion{kind: assertContains, text: "item"}, true, []Location{{1, 2}, {2, 2}}},
+		{"contains missing", assertion{kind: assertContains, text: "Help"}, false, nil},
+		{"not contains", assertion{kind: assertNotContains, text: "Error"}, true, nil},
+		{"not contains found", assertion{kind: assertNotContains, text: "ready"}, false, []Location{{3, 8}}},
+		{"regex", regex(`item (one|two)`), true, []Location{{1, 2}, {2, 2}}},
+		{"regex no match", regex(`^Error`), false, nil},
+		{"count", assertion{kind: assertCount, text: "item", count: 2}, true, []Location{{1, 2}, {2, 2}}},
+		{"count zero", assertion{kind: assertCount, text: "Error", count: 0}, true, nil},
+		{"count mismatch", assertion{kind: assertCount, text: "item", count: 1}, false, []Location{{1, 2}, {2, 2}}},
+		{"region", region("3,0,10:Status"), true, []Location{{3, 0}}},
+		{"region elsewhere", region("0,0,20,2:Status"), false, nil},
+		{"region to end of row", region("0,6:Edit"), true, []Location{{0, 7}}},
+		{"region off screen", region("30,0,10:Status"), false, nil},
+		{"highlighted", assertion{kind: assertHighlighted, text: "one"}, true, []Location{{1, 7}}},
+		{"not highlighted", assertion{kind: assertHighlighted, text: "two"}, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := tt.assertion.evaluate(capture)
+			if r.Passed != tt.wantPassed {
+				t.Errorf("Passed = %v, want %v (%s)", r.Passed, tt.wantPassed, r.Message)
+			}
+			if !reflect.DeepEqual(r.Matches, tt.wantMatches) {
+				t.Errorf("Matches = %v, want %v", r.Matches, tt.wantMatches)
+			}
+			if r.Count != len(r.Matches) {
+				t.Errorf("Count = %d, want %d", r.Count, len(r.Matches))
+			}
+			if !r.Passed && r.Message == "" {
+				t.Error("failed assertion has no message")
+			}
+		})
+	}
+}
+
+func TestMissingStepResults(t *testing.T) {
+	asserts := []stepAssertion{
+		{step: 0, assertion: assertion{kind: assertContains, text: "a"}},
+		{step: 3, assertion: assertion{kind: assertContains, text: "b"}},
+	}
+	results := missingStepResults(asserts, 2)
+	if len(results) != 1 || *results[0].Step != 3 || results[0].Passed {
+		t.Errorf("missingStepResults() = %+v, want one failure for step 3", results)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/creack/pty"
+)
+
+// attachDetachKey (ctrl-]) ends an attach without stopping the session.
+const attachDetachKey = 0x1d
+
+// runAttach lets a human watch and type into a session of a running serve
+// command. The screen comes from the session's emulator through its event
+// stream; keys typed here are sent to the session as they are.
+func runAttach(args []string) int {
+	fs := flag.NewFlagSet("attach", flag.ExitOnError)
+	server := fs.String("server", "http://127.0.0.1:7777", "URL of the serve command")
+	resize := fs.Bool("resize", false, "Resize the session to this terminal first")
+	token := fs.String("token", os.Getenv(serveTokenEnv), "The serve command's bearer token (default $"+serveTokenEnv+")")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: tui-goggles attach [-server url] [-token t] [-resize] SESSION_ID")
+		fmt.Fprintln(os.Stderr, "Take over a serve session's screen and keyboard; ctrl-] detaches.")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return ExitGeneralError
+	}
+	base := strings.TrimRight(*server, "/") + "/sessions/" + url.PathEscape(fs.Arg(0))
+
+	client := &attachClient{base: base, token: *token}
+	if *resize {
+		rows, cols, err := pty.Getsize(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -resize: %v\n", err)
+			return ExitGeneralError
+		}
+		if err := client.post("resize", map[string]any{"cols": cols, "rows": rows}); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return ExitGeneralError
+		}
+	}
+
+	stream := base + "/events?format=ansi"
+	if rows, cols, err := pty.Getsize(os.Stdout); err == nil && rows > 0 && cols > 0 {
+		stream += fmt.Sprintf("&cols=%d&rows=%d", cols, rows)
+	}
+	req, err := http.NewRequest(http.MethodGet, stream, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return ExitGeneralError
+	}
+	client.authorize(req)
+	events, err := http.DefaultClient.Do(req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return ExitGeneralError
+	}
+	defer events.Body.Close()
+	if events.StatusCode != http.StatusOK {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", apiErrorMessage(events))
+		return ExitGeneralError
+	}
+
+	restore, err := makeRaw(os.Stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: attach needs a terminal: %v\n", err)
+		return ExitGeneralError
+	}
+	// The alternate screen keeps the session's screen out of our scrollback
+	fmt.Print("\x1b[?1049h\x1b[H\x1b[2J")
+
+	ended := make(chan string, 2)
+	go func() { ended <- client.forwardInput(os.Stdin) }()
+	go func() { ended <- client.drawEvents(events.Body) }()
+	reason := <-ended
+
+	fmt.Print("\x1b[0m\x1b[?25h\x1b[?1049l")
+	restore()
+	fmt.Fprintln(os.Stderr, reason)
+	return ExitSuccess
+}
+
+type attachClient struct {
+	base  string
+	token string
+}
+
+// authorize adds the serve token to a request.
+func (c *attachClient) authorize(req *http.Request) {
+	req.Header.Set("Authorization", "Bearer "+c.token)
+}
+
+// forwardInput sends what is typed to the session until the detach key.
+func (c *attachClient) forwardInput(r io.Reader) string {
+	buf := make([]byte, 4096)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			input := buf[:n]
+			detach := false
+			if i := bytes.IndexByte(input, attachDetachKey); i >= 0 {
+				input, detach = input[:i], true
+			}
+			if len(input) > 0 {
+				if err := c.post("keys", map[string]any{"text": string(input), "no_wait": true}); err != nil {
+					return "Error: " + err.Error()
+				}
+			}
+			if detach {
+				return "detached"
+			}
+		}
+		if err != nil {
+			return "Error: reading input: " + err.Error()
+		}
+	}
+}
+
+// drawEvents draws the screen events of an ?format=ansi event stream until
+// the session's command exits.
+func (c *attachClient) drawEvents(r io.Reader) string {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	event := ""
+	cols, rows := 0, 0
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: ") && event == "screen":
+			var frame ansiFrame
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &frame); err != nil {
+				return "Error: invalid screen event: " + err.Error()
+			}
+			if frame.Cols != cols || frame.Rows != rows {
+				// Clear what a larger frame left behind
+				cols, rows = frame.Cols, frame.Rows
+				fmt.Print("\x1b[H\x1b[2J")
+			}
+			fmt.Print(frame.ANSI)
+		case strings.HasPrefix(line, "data: ") && event == "exit":
+			// Leave the last screen up for a moment
+			time.Sleep(500 * time.Millisecond)
+			return "session command exited"
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "Error: " + err.Error()
+	}
+	return "session closed"
+}
+
+// post sends a JSON request to one of the session's endpoints.
+func (c *attachClient) post(action string, body any) error {
+	data, _ := json.Marshal(body)
+	req, err := http.NewRequest(http.MethodPost, c.base+"/"+action, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.authorize(req)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(apiErrorMessage(resp))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// apiErrorMessage extracts the error from a failed API response.
+func apiErrorMessage(resp *http.Response) string {
+	var body struct {
+		Error string `json:"error"`
+	}
+	if json.NewDecoder(resp.Body).Decode(&body) == nil && body.Error != "" {
+		return body.Error
+	}
+	return resp.Status
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// formatCompact renders a capture with as little whitespace as possible:
+// trailing spaces are stripped, runs of blank lines are collapsed into a
+// single marker line. The cursor itself is marked when the capture is taken
+// (see renderScreen).
+func formatCompact(result CaptureResult, lineNumbers bool) string {
+	lines := strings.Split(strings.TrimRight(result.Screen, "\n"), "\n")
+
+	var sb strings.Builder
+	blankStart := -1
+
+	flushBlank := func(end int) {
+		if blankStart < 0 {
+			return
+		}
+		count := end - blankStart
+		if count == 1 {
+			writeCompactLine(&sb, blankStart, "", lineNumbers)
+		} else {
+			writeCompactLine(&sb, blankStart, fmt.Sprintf("~ %d blank lines", count), lineNumbers)
+		}
+		blankStart = -1
+	}
+
+	for row, line := range lines {
+		line = strings.TrimRight(line, " ")
+
+		if line == "" {
+			if blankStart < 0 {
+				blankStart = row
+			}
+			continue
+		}
+
+		flushBlank(row)
+		writeCompactLine(&sb, row, line, lineNumbers)
+	}
+
+	// Trailing blank lines carry no information, so they are dropped
+	// rather than collapsed.
+	return sb.String()
+}
+
+func writeCompactLine(sb *strings.Builder, row int, line string, lineNumbers bool) {
+	if lineNumbers {
+		sb.WriteString(fmt.Sprintf("%3d|", row))
+		if line != "" {
+			sb.WriteString(" ")
+		}
+	}
+	sb.WriteString(line)
+	sb.WriteString("\n")
+}
@@ -0,0 +1,57 @@
+package main
+
+import "testing"
+
+func TestFormatCompact(t *testing.T) {
+	tests := []struct {
+		name        string
+		screen      string
+		lineNumbers bool
+		want        string
+	}{
+		{
+			name:   "trailing spaces stripped",
+			screen: "Files   \n  > one  ",
+			want:   "Files\n  > one\n",
+		},
+		{
+			name:   "blank run collapsed",
+			screen: "top\n\n   \n\nbottom",
+			want:   "top\n~ 3 blank lines\nbottom\n",
+		},
+		{
+			name:   "single blank line kept",
+			screen: "top\n\nbottom",
+			want:   "top\n\nbottom\n",
+		},
+		{
+			name:   "trailing blank lines dropped",
+			screen: "top\nbottom\n\n  \n\n",
+			want:   "top\nbottom\n",
+		},
+		{
+			name:   "leading blank lines collapsed",
+			screen: "\n\ntext",
+			want:   "~ 2 blank lines\ntext\n",
+		},
+		{
+			name:   "empty screen",
+			screen: "\n\n\n",
+			want:   "",
+		},
+		{
+			name:        "line numbers survive the collapse",
+			screen:      "top\n\n\n\nmiddle\n\nbottom\n\n",
+			lineNumbers: true,
+			want:        "  0| top\n  1| ~ 3 blank lines\n  4| middle\n  5|\n  6| bottom\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := formatCompact(CaptureResult{Screen: tt.screen}, tt.lineNumbers)
+			if got != tt.want {
+				t.Errorf("formatCompact() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ScreenDiff describes how a capture differs from the capture before it.
+type ScreenDiff struct {
+	ChangedRows []int        `json:"changed_rows"`
+	Regions     []DiffRegion `json:"regions,omitempty"`
+}
+
+// DiffRegion is a run of consecutive changed rows. StartCol and EndCol bound
+// the columns that changed on any row of the region (EndCol is exclusive).
+type DiffRegion struct {
+	StartRow int      `json:"start_row"`
+	EndRow   int      `json:"end_row"`
+	StartCol int      `json:"start_col"`
+	EndCol   int      `json:"end_col"`
+	Old      []string `json:"old"`
+	New      []string `json:"new"`
+}
+
+// diffScreens compares two rendered screens row by row.
+func diffScreens(prev, cur string) *ScreenDiff {
+	oldLines := strings.Split(prev, "\n")
+	newLines := strings.Split(cur, "\n")
+
+	n := len(oldLines)
+	if len(newLines) > n {
+		n = len(newLines)
+	}
+
+	diff := &ScreenDiff{ChangedRows: []int{}}
+	var region *DiffRegion
+
+	for row := 0; row < n; row++ {
+		oldLine := lineAt(oldLines, row)
+		newLine := lineAt(newLines, row)
+
+		if oldLine == newLine {
+			if region != nil {
+				diff.Regions = append(diff.Regions, *region)
+				region = nil
+			}
+			continue
+		}
+
+		diff.ChangedRows = append(diff.ChangedRows, row)
+
+		startCol, endCol := changedSpan(oldLine, newLine)
+		if region == nil {
+			region = &DiffRegion{
+				StartRow: row,
+				StartCol: startCol,
+				EndCol:   endCol,
+			}
+		}
+		region.EndRow = row
+		if startCol < region.StartCol {
+			region.StartCol = startCol
+		}
+		if endCol > region.EndCol {
+			region.EndCol = endCol
+		}
+		region.Old = append(region.Old, strings.TrimRight(oldLine, " "))
+		region.New = append(region.New, strings.TrimRight(newLine, " "))
+	}
+
+	if region != nil {
+		diff.Regions = append(diff.Regions, *region)
+	}
+
+	return diff
+}
+
+// lineAt returns the given row, or an empty line if the screen is shorter
+// (for example after -trim).
+func lineAt(lines []string, row int) string {
+	if row < len(lines) {
+		return lines[row]
+	}
+	return ""
+}
+
+// changedSpan returns the column range [start, end) in which two lines differ.
+func changedSpan(a, b string) (start, end int) {
+	ra := []rune(a)
+	rb := []rune(b)
+
+	for start < len(ra) && start < len(rb) && ra[start] == rb[start] {
+		start++
+	}
+
+	ea, eb := len(ra), len(rb)
+	for ea > start && eb > start && ra[ea-1] == rb[eb-1] {
+		ea--
+		eb--
+	}
+
+	end = ea
+	if eb > end {
+		end = eb
+	}
+	return start, end
+}
+
+// formatDiffText renders a diff with changed lines marked, for text output.
+func formatDiffText(diff *ScreenDiff) string {
+	if diff == nil || len(diff.ChangedRows) == 0 {
+		return "(no changes)\n"
+	}
+
+	var sb strings.Builder
+	for _, region := range diff.Regions {
+		if region.StartRow == region.EndRow {
+			sb.WriteString(fmt.Sprintf("@@ row %d @@\n", region.StartRow))
+		} else {
+			sb.WriteString(fmt.Sprintf("@@ rows %d-%d @@\n", region.StartRow, region.EndRow))
+		}
+		for i, line := range region.Old {
+			sb.WriteString(fmt.Sprintf("-%3d| %s\n", region.StartRow+i, line))
+		}
+		for i, line := range region.New {
+			sb.WriteString(fmt.Sprintf("+%3d| %s\n", region.StartRow+i, line))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffScreens(t *testing.T) {
+	tests := []struct {
+		name string
+		prev string
+		cur  string
+		want *ScreenDiff
+	}{
+		{
+			name: "unchanged",
+			prev: "a\nb",
+			cur:  "a\nb",
+			want: &ScreenDiff{ChangedRows: []int{}},
+		},
+		{
+			name: "one cell",
+			prev: "count: 1\nfooter",
+			cur:  "count: 2\nfooter",
+			want: &ScreenDiff{
+				ChangedRows: []int{0},
+				Regions: []DiffRegion{
+					{StartRow: 0, EndRow: 0, StartCol: 7, EndCol: 8, Old: []string{"count: 1"}, New: []string{"count: 2"}},
+				},
+			},
+		},
+		{
+			name: "consecutive rows form one region",
+			prev: "> one\n  two\nend",
+			cur:  "  one\n> two\nend",
+			want: &ScreenDiff{
+				ChangedRows: []int{0, 1},
+				Regions: []DiffRegion{
+					{StartRow: 0, EndRow: 1, StartCol: 0, EndCol: 1, Old: []string{"> one", "  two"}, New: []string{"  one", "> two"}},
+				},
+			},
+		},
+		{
+			name: "separate regions",
+			prev: "a\nsame\nb",
+			cur:  "x\nsame\ny",
+			want: &ScreenDiff{
+				ChangedRows: []int{0, 2},
+				Regions: []DiffRegion{
+					{StartRow: 0, EndRow: 0, StartCol: 0, EndCol: 1, Old: []string{"a"}, New: []string{"x"}},
+					{StartRow: 2, EndRow: 2, StartCol: 0, EndCol: 1, Old: []string{"b"}, New: []string{"y"}},
+				},
+			},
+		},
+		{
+			name: "shorter screen after trim",
+			prev: "a\nb\nc",
+			cur:  "a",
+			want: &ScreenDiff{
+				ChangedRows: []int{1, 2},
+				Regions: []DiffRegion{
+					{StartRow: 1, EndRow: 2, StartCol: 0, EndCol: 1, Old: []string{"b", "c"}, New: []string{"", ""}},
+				},
+			},
+		},
+		{
+			name: "multibyte characters are counted in runes, not bytes",
+			prev: "✓ done   ",
+			cur:  "✗ failed ",
+			want: &ScreenDiff{
+				ChangedRows: []int{0},
+				Regions: []DiffRegion{
+					{StartRow: 0, EndRow: 0, StartCol: 0, EndCol: 8, Old: []string{"✓ done"}, New: []string{"✗ failed"}},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diffScreens(tt.prev, tt.cur)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffScreens() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChangedSpan(t *testing.T) {
+	tests := []struct {
+		a, b       string
+		start, end int
+	}{
+		{"abc", "abc", 3, 3},
+		{"abc", "abd", 2, 3},
+		{"abc", "xbc", 0, 1},
+		{"abc", "abXYc", 2, 4},
+		{"abcd", "ad", 1, 3},
+		{"", "new", 0, 3},
+	}
+	for _, tt := range tests {
+		start, end := changedSpan(tt.a, tt.b)
+		if start != tt.start || end != tt.end {
+			t.Errorf("changedSpan(%q, %q) = %d, %d, want %d, %d", tt.a, tt.b, start, end, tt.start, tt.end)
+		}
+	}
+}
+
+func TestFormatDiffText(t *testing.T) {
+	if got := formatDiffText(&ScreenDiff{ChangedRows: []int{}}); got != "(no changes)\n" {
+		t.Errorf("formatDiffText(no changes) = %q", got)
+	}
+
+	diff := diffScreens("a\nb\nc", "a\nB\nC")
+	want := "@@ rows 1-2 @@\n-  1| b\n-  2| c\n+  1| B\n+  2| C\n"
+	if got := formatDiffText(diff); got != want {
+		t.Errorf("formatDiffText() =\n%s\nwant\n%s", got, want)
+	}
+}
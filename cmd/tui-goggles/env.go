@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// cleanPath is the PATH of a clean environment. The command itself is
+// still looked up in our PATH; this only affects what it runs in turn.
+const cleanPath = "/usr/local/bin:/usr/bin:/bin"
+
+// cleanEnv builds the fixed environment for -clean-env: a fixed PATH, a
+// UTF-8 C locale, UTC, HOME in the given (empty) directory and the terminal
+// size. Host variables named in allow are passed through unchanged, in
+// place of the fixed value if there is one (as for PATH).
+func cleanEnv(home string, cols, rows int, allow []string) []string {
+	env := []string{
+		"PATH=" + cleanPath,
+		"HOME=" + home,
+		"LANG=C.UTF-8",
+		"LC_ALL=C.UTF-8",
+		"TZ=UTC",
+		fmt.Sprintf("COLUMNS=%d", cols),
+		fmt.Sprintf("LINES=%d", rows),
+	}
+	for _, name := range allow {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		env = slices.DeleteFunc(env, func(kv string) bool {
+			return strings.HasPrefix(kv, name+"=")
+		})
+		env = append(env, name+"="+value)
+	}
+	return env
+}
+
+// envMap converts an environment list to a map for JSON output. As with
+// exec, the last entry for a name wins.
+func envMap(env []string) map[string]string {
+	m := make(map[string]string, len(env))
+	for _, kv := range env {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok {
+			continue
+		}
+		m[name] = value
+	}
+	return m
+}
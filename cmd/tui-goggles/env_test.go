@@ -0,0 +1,64 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCleanEnv(t *testing.T) {
+	t.Setenv("PATH", "/host/bin")
+	t.Setenv("GOPATH", "/host/go")
+	t.Setenv("TZ", "Europe/Berlin")
+	t.Setenv("SECRET", "hunter2")
+
+	tests := []struct {
+		name  string
+		allow []string
+		extra []string // as given with -env, appended after
+		want  map[string]string
+	}{
+		{
+			name: "fixed",
+			want: map[string]string{
+				"PATH": cleanPath, "HOME": "/tmp/home", "LANG": "C.UTF-8", "LC_ALL": "C.UTF-8",
+				"TZ": "UTC", "COLUMNS": "80", "LINES": "24",
+			},
+		},
+		{
+			name:  "allowed variables pass through",
+			allow: []string{"GOPATH", "UNSET"},
+			want: map[string]string{
+				"PATH": cleanPath, "HOME": "/tmp/home", "LANG": "C.UTF-8", "LC_ALL": "C.UTF-8",
+				"TZ": "UTC", "COLUMNS": "80", "LINES": "24", "GOPATH": "/host/go",
+			},
+		},
+		{
+			name:  "allowed variables replace fixed ones",
+			allow: []string{"PATH", "TZ"},
+			want: map[string]string{
+				"PATH": "/host/bin", "HOME": "/tmp/home", "LANG": "C.UTF-8", "LC_ALL": "C.UTF-8",
+				"TZ": "Europe/Berlin", "COLUMNS": "80", "LINES": "24",
+			},
+		},
+		{
+			name:  "-env overrides everything",
+			allow: []string{"PATH"},
+			extra: []string{"PATH=/opt/bin", "LANG=de_DE.UTF-8", "FOO=bar"},
+			want: map[string]string{
+				"PATH": "/opt/bin", "HOME": "/tmp/home", "LANG": "de_DE.UTF-8", "LC_ALL": "C.UTF-8",
+				"TZ": "UTC", "COLUMNS": "80", "LINES": "24", "FOO": "bar",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := append(cleanEnv("/tmp/home", 80, 24, tt.allow), tt.extra...)
+			if got := envMap(env); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("environment = %v, want %v", got, tt.want)
+			}
+			if tt.extra == nil && len(env) != len(tt.want) {
+				t.Errorf("environment has %d entries for %d variables: %q", len(env), len(tt.want), env)
+			}
+		})
+	}
+}
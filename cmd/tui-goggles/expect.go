@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/your-username/tui-goggles/internal/terminal"
+)
+
+// parseExpectRule parses an -expect or -expect-line rule of the form
+// PATTERN=>KEYS. The split is on the last "=>", so patterns may contain it;
+// KEYS are parsed like -keys.
+func parseExpectRule(spec string, lastLine bool) (terminal.ExpectRule, error) {
+	i := strings.LastIndex(spec, "=>")
+	if i < 0 {
+		return terminal.ExpectRule{}, fmt.Errorf("invalid rule %q (format: PATTERN=>KEYS)", spec)
+	}
+	pattern, keys := spec[:i], spec[i+2:]
+	if pattern == "" {
+		return terminal.ExpectRule{}, fmt.Errorf("invalid rule %q: empty pattern", spec)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return terminal.ExpectRule{}, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+	}
+	send := keySequence(keys)
+	if send == "" {
+		return terminal.ExpectRule{}, fmt.Errorf("invalid rule %q: no keys to send", spec)
+	}
+	return terminal.ExpectRule{Pattern: re, LastLine: lastLine, Send: send}, nil
+}
+
+// expectFailure describes an Expect run that did not end successfully, or
+// returns "" if it did.
+func expectFailure(result terminal.ExpectResult, until bool) string {
+	switch result.Reason {
+	case terminal.ExpectTimeout:
+		return "timeout waiting for expect rules to finish"
+	case terminal.ExpectMaxIterations:
+		return fmt.Sprintf("expect rules fired %d times without finishing (see -expect-max)", result.Iterations)
+	case terminal.ExpectExited:
+		if until {
+			return "command exited before -expect-until matched"
+		}
+	}
+	return ""
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/your-username/tui-goggles/internal/terminal"
+)
+
+// Assertion kinds checked against the recorded frames.
+const (
+	assertSeen    = "seen"
+	assertNotSeen = "not_seen"
+)
+
+// stepLog records when each key step of the run began, so recorded frames
+// can be attributed to the step they were drawn in. Step 0 is everything
+// before the first key. A nil *stepLog records nothing.
+type stepLog struct {
+	marks []stepMark
+}
+
+type stepMark struct {
+	key string
+	at  time.Time
+}
+
+// mark starts the next step.
+func (l *stepLog) mark(key string) {
+	l.markAt(key, time.Now())
+}
+
+// markAt starts the next step as of an earlier time, for phases (such as
+// -expect) that only turn out to be a step once they are over.
+func (l *stepLog) markAt(key string, at time.Time) {
+	if l == nil {
+		return
+	}
+	l.marks = append(l.marks, stepMark{key: key, at: at})
+}
+
+// stepAt returns the step that was in progress at the given time and the
+// key that started it ("" for step 0).
+func (l *stepLog) stepAt(at time.Time) (step int, key string) {
+	if l == nil {
+		return 0, ""
+	}
+	for i, m := range l.marks {
+		if m.at.After(at) {
+			break
+		}
+		step, key = i+1, m.key
+	}
+	return step, key
+}
+
+// FrameInfo is a distinct screen state seen during the run (-record-frames).
+type FrameInfo struct {
+	Index int `json:"index"`
+	// ElapsedMs is when the frame was drawn, relative to the start of the
+	// run, and DurationMs how long it stayed up.
+	ElapsedMs  int64 `json:"elapsed_ms"`
+	DurationMs int64 `json:"duration_ms"`
+	// Step is the key step the frame was drawn in (0 is before the first
+	// key) and Key the key that started it.
+	Step int    `json:"step"`
+	Key  string `json:"key,omitempty"`
+	// Writes counts the output batches that drew this same screen.
+	Writes int    `json:"writes"`
+	Screen string `json:"screen"`
+}
+
+// frameInfos attributes the recorded frames to steps and times them
+// against the start of the run; the last frame lasts until end.
+func frameInfos(frames []terminal.Frame, steps *stepLog, start, end time.Time, trim bool) []FrameInfo {
+	infos := make([]FrameInfo, len(frames))
+	for i, f := range frames {
+		screen := f.Screen
+		if trim {
+			screen = trimTrailingBlankLines(screen)
+		}
+		until := end
+		if i+1 < len(frames) {
+			until = frames[i+1].Time
+		}
+		step, key := steps.stepAt(f.Time)
+		infos[i] = FrameInfo{
+			Index:      i,
+			ElapsedMs:  f.Time.Sub(start).Milliseconds(),
+			DurationMs: until.Sub(f.Time).Milliseconds(),
+			Step:       step,
+			Key:        key,
+			Writes:     f.Writes,
+			Screen:     screen,
+		}
+	}
+	return infos
+}
+
+// formatFramesText renders the -frames timeline for the text formats, one
+// screen per frame under a header with its timing and step.
+func formatFramesText(frames []FrameInfo, cfg config) string {
+	var sb strings.Builder
+	for _, f := range frames {
+		step := "before any key"
+		if f.Step > 0 {
+			step = fmt.Sprintf("step %d, after %s", f.Step, f.Key)
+		}
+		redrawn := ""
+		if f.Writes > 1 {
+			redrawn = fmt.Sprintf(", drawn %d times", f.Writes)
+		}
+		fmt.Fprintf(&sb, "\n--- Frame %d at %dms for %dms (%s%s) ---\n", f.Index, f.ElapsedMs, f.DurationMs, step, redrawn)
+		sb.WriteString(formatScreen(CaptureResult{Screen: f.Screen}, cfg))
+	}
+	return sb.String()
+}
+
+// seenAssertion is a parsed -assert-seen or -assert-not-seen flag: whether
+// text was on screen in any frame drawn during a range of steps.
+type seenAssertion struct {
+	kind string
+	text string
+	// from and to are the first and last step of the range; to is -1 for
+	// the end of the run.
+	from, to int
+}
+
+// parseSeenAssertion parses "text", "A:text" or "A-B:text". A prefix is only
+// taken as a step range if it is numeric, so the text may contain colons.
+func parseSeenAssertion(kind, spec string) (seenAssertion, error) {
+	a := seenAssertion{kind: kind, text: spec, to: -1}
+	if prefix, text, ok := strings.Cut(spec, ":"); ok {
+		fromStr, toStr, isRange := strings.Cut(prefix, "-")
+		from, err := strconv.Atoi(fromStr)
+		if err == nil && from >= 0 {
+			to := from
+			if isRange {
+				to, err = strconv.Atoi(toStr)
+			}
+			if err == nil {
+				if to < from {
+					return a, fmt.Errorf("invalid step range %q: %d is before %d", prefix, to, from)
+				}
+				a.text, a.from, a.to = text, from, to
+			}
+		}
+	}
+	if a.text == "" {
+		return a, fmt.Errorf("invalid assertion %q: empty text", spec)
+	}
+	return a, nil
+}
+
+// steps describes the assertion's step range.
+func (a seenAssertion) steps() string {
+	switch {
+	case a.to < 0 && a.from == 0:
+		return "during the run"
+	case a.to < 0:
+		return fmt.Sprintf("from step %d on", a.from)
+	case a.from == a.to:
+		return fmt.Sprintf("in step %d", a.from)
+	default:
+		return fmt.Sprintf("between step %d and step %d", a.from, a.to)
+	}
+}
+
+// evaluate checks the assertion against the recorded frames. A frame counts
+// for every step it stays on screen through, so the frame that was current
+// when step from began is checked along with those drawn in the range.
+// Matches are the positions in the first frame the text was seen in.
+func (a seenAssertion) evaluate(frames []FrameInfo) AssertionResult {
+	res := AssertionResult{Kind: a.kind, Text: a.text}
+	start := 0
+	for i, f := range frames {
+		if f.Step >= a.from {
+			break
+		}
+		start = i
+	}
+	checked := 0
+	for _, f := range frames[start:] {
+		if a.to >= 0 && f.Step > a.to {
+			break
+		}
+		checked++
+		if matches := findAll(f.Screen, a.text); len(matches) > 0 {
+			frame := f.Index
+			res.Frame = &frame
+			res.Matches = matches
+			break
+		}
+	}
+	res.Count = len(res.Matches)
+
+	seen := res.Frame != nil
+	res.Passed = seen == (a.kind == assertSeen)
+	switch {
+	case res.Passed:
+	case seen:
+		m := res.Matches[0]
+		res.Message = fmt.Sprintf("text %q appeared %s (frame %d, row %d, col %d)", a.text, a.steps(), *res.Frame, m.Row, m.Col)
+	default:
+		res.Message = fmt.Sprintf("text %q never appeared %s (%d frames)", a.text, a.steps(), checked)
+	}
+	return res
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/your-username/tui-goggles/internal/terminal"
+)
+
+func TestStepLog(t *testing.T) {
+	start := time.Now()
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+
+	steps := &stepLog{}
+	steps.markAt("down", at(100))
+	steps.markAt("enter", at(200))
+
+	tests := []struct {
+		ms       int
+		wantStep int
+		wantKey  string
+	}{
+		{0, 0, ""},
+		{99, 0, ""},
+		{100, 1, "down"},
+		{150, 1, "down"},
+		{200, 2, "enter"},
+		{1000, 2, "enter"},
+	}
+	for _, tt := range tests {
+		step, key := steps.stepAt(at(tt.ms))
+		if step != tt.wantStep || key != tt.wantKey {
+			t.Errorf("stepAt(%dms) = %d, %q, want %d, %q", tt.ms, step, key, tt.wantStep, tt.wantKey)
+		}
+	}
+
+	var none *stepLog
+	none.mark("x")
+	if step, key := none.stepAt(at(500)); step != 0 || key != "" {
+		t.Errorf("nil stepLog: stepAt() = %d, %q, want 0, \"\"", step, key)
+	}
+}
+
+func TestFrameInfos(t *testing.T) {
+	start := time.Now()
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+
+	steps := &stepLog{}
+	steps.markAt("enter", at(150))
+	frames := []terminal.Frame{
+		{Time: at(10), Screen: "Loading\n\n", Writes: 1},
+		{Time: at(100), Screen: "Menu\n\n", Writes: 3},
+		{Time: at(160), Screen: "Done\n\n", Writes: 1},
+	}
+
+	got := frameInfos(frames, steps, start, at(400), true)
+	want := []FrameInfo{
+		{Index: 0, ElapsedMs: 10, DurationMs: 90, Step: 0, Writes: 1, Screen: "Loading"},
+		{Index: 1, ElapsedMs: 100, DurationMs: 60, Step: 0, Writes: 3, Screen: "Menu"},
+		{Index: 2, ElapsedMs: 160, DurationMs: 240, Step: 1, Key: "enter", Writes: 1, Screen: "Done"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("frameInfos() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestParseSeenAssertion(t *testing.T) {
+	tests := []struct {
+		spec     string
+		wantText string
+		wantFrom int
+		wantTo   int
+		wantErr  bool
+	}{
+		{spec: "Loading", wantText: "Loading", wantFrom: 0, wantTo: -1},
+		{spec: "2:Saved", wantText: "Saved", wantFrom: 2, wantTo: 2},
+		{spec: "1-3:Saved", wantText: "Saved", wantFrom: 1, wantTo: 3},
+		{spec: "Time: 12:00", wantText: "Time: 12:00", wantFrom: 0, wantTo: -1},
+		{spec: "a-b:text", wantText: "a-b:text", wantFrom: 0, wantTo: -1},
+		{spec: "1:a:b", wantText: "a:b", wantFrom: 1, wantTo: 1},
+
+		{spec: "", wantErr: true},
+		{spec: "2:", wantErr: true},
+		{spec: "3-1:text", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			a, err := parseSeenAssertion(assertSeen, tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseSeenAssertion(%q) = %+v, want error", tt.spec, a)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if a.text != tt.wantText || a.from != tt.wantFrom || a.to != tt.wantTo {
+				t.Errorf("parseSeenAssertion(%q) = %+v, want text %q, steps %d-%d", tt.spec, a, tt.wantText, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
+
+func TestSeenAssertionEvaluate(t *testing.T) {
+	frames := []FrameInfo{
+		{Index: 0, Step: 0, Screen: "Loading..."},
+		{Index: 1, Step: 1, Screen: "Menu\n  Saving"},
+		{Index: 2, Step: 2, Screen: "Menu\n  Saved"},
+	}
+	tests := []struct {
+		kind      string
+		spec      string
+		wantPass  bool
+		wantFrame int // -1 for none
+	}{
+		{assertSeen, "Loading", true, 0},
+		{assertSeen, "Saving", true, 1},
+		{assertSeen, "Error", false, -1},
+		{assertSeen, "2:Saved", true, 2},
+		{assertSeen, "0-1:Saved", false, -1},
+		{assertSeen, "1-:Saving", false, -1},
+		{assertNotSeen, "Error", true, -1},
+		{assertNotSeen, "Saving", false, 1},
+		{assertNotSeen, "2:Loading", true, -1},
+		// The frame on screen when the range begins carries over into it
+		{assertSeen, "2:Menu", true, 1},
+		{assertNotSeen, "2:Saving", false, 1},
+		{assertSeen, "1:Loading", true, 0},
+		{assertSeen, "3:Saved", true, 2},
+		{assertNotSeen, "3-5:Saved", false, 2},
+		{assertNotSeen, "3:Loading", true, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.kind+" "+tt.spec, func(t *testing.T) {
+			a, err := parseSeenAssertion(tt.kind, tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := a.evaluate(frames)
+			if r.Passed != tt.wantPass {
+				t.Errorf("Passed = %v, want %v (%s)", r.Passed, tt.wantPass, r.Message)
+			}
+			frame := -1
+			if r.Frame != nil {
+				frame = *r.Frame
+			}
+			if frame != tt.wantFrame {
+				t.Errorf("Frame = %d, want %d", frame, tt.wantFrame)
+			}
+			if !r.Passed && r.Message == "" {
+				t.Error("failed assertion has no message")
+			}
+		})
+	}
+}
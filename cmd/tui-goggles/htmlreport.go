@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/your-username/tui-goggles/internal/render"
+)
+
+// htmlReport is the data behind the -html session report.
+type htmlReport struct {
+	Command   string
+	Generated string
+	Passed    bool
+	Timing    *TimingInfo
+	Cases     []htmlCase
+	Frames    []htmlFrame
+}
+
+// htmlCase is a reported expectation (see testCase).
+type htmlCase struct {
+	Name    string
+	Class   string
+	Passed  bool
+	Message string
+}
+
+// htmlFrame is one capture in the report's timeline.
+type htmlFrame struct {
+	Index      int
+	Title      string
+	Wait       string
+	WaitMs     int64
+	ElapsedMs  int64
+	Stable     bool
+	Screen     template.HTML
+	Checks     map[string]bool
+	Assertions []AssertionResult
+	Passed     bool
+}
+
+// writeHTMLReport writes a self-contained HTML page with every capture of
+// the run, the step that led to it, timings and expectation outcomes.
+func writeHTMLReport(path string, final CaptureResult, captures []CaptureResult, cfg config, timing *TimingInfo, suite *testSuite) error {
+	report := htmlReport{
+		Command:   suite.name,
+		Generated: time.Now().Format(time.RFC3339),
+		Passed:    suite.failures() == 0,
+		Timing:    timing,
+	}
+	for _, tc := range suite.cases {
+		report.Cases = append(report.Cases, htmlCase{Name: tc.name, Class: tc.class, Passed: tc.passed, Message: tc.message})
+	}
+
+	if len(captures) == 0 {
+		// A scripted run only has the final capture; describe everything
+		// that was done before it.
+		captures = []CaptureResult{final}
+		captures[0].Step = &StepInfo{Wait: scriptedSteps(cfg), ElapsedMs: timing.TotalMs}
+	}
+
+	for i, c := range captures {
+		frame := htmlFrame{
+			Index:      i,
+			Title:      "Final screen",
+			Stable:     c.Stable,
+			Screen:     template.HTML(render.HTML(c.view)),
+			Checks:     c.Checks,
+			Assertions: c.Assertions,
+			Passed:     true,
+		}
+		if c.Step != nil {
+			frame.Wait = c.Step.Wait
+			frame.WaitMs = c.Step.WaitMs
+			frame.ElapsedMs = c.Step.ElapsedMs
+			switch {
+			case c.Step.Key != "":
+				frame.Title = fmt.Sprintf("Step %d: key %s", i, c.Step.Key)
+			case cfg.captureEach:
+				frame.Title = "Step 0: initial screen"
+			}
+		}
+		for _, r := range c.Assertions {
+			if !r.Passed {
+				frame.Passed = false
+			}
+		}
+		report.Frames = append(report.Frames, frame)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := htmlReportTemplate.Execute(f, report); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// scriptedSteps describes the waits and keys of a run without
+// -capture-each, in the order they were performed.
+func scriptedSteps(cfg config) string {
+	steps := initialWait(cfg)
+	if keys := strings.Fields(cfg.keys); len(keys) > 0 {
+		steps += ", keys " + strings.Join(keys, " ") + ", stable"
+	}
+	return steps
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>tui-goggles: {{.Command}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 0; display: flex; height: 100vh; color: #222; }
+nav { width: 18rem; overflow-y: auto; border-right: 1px solid #ddd; background: #f7f7f7; }
+nav h1 { font-size: 1rem; margin: 1rem; word-break: break-all; }
+nav ol { list-style: none; margin: 0; padding: 0; }
+nav li { padding: .5rem 1rem; cursor: pointer; border-left: 4px solid transparent; }
+nav li.current { background: #e4e9f2; border-left-color: #3867d6; }
+nav li .ms { color: #777; font-size: .85em; }
+main { flex: 1; overflow: auto; padding: 1rem 2rem; }
+table { border-collapse: collapse; margin: .5rem 0 1rem; }
+td, th { text-align: left; padding: .2rem .8rem .2rem 0; vertical-align: top; }
+.pass { color: #1e8e3e; }
+.fail { color: #d93025; }
+.frame { display: none; }
+.frame.current { display: block; }
+.controls { margin: .5rem 0; }
+.controls button { font-size: 1rem; padding: .2rem .8rem; }
+pre.screen { display: inline-block; margin: 0; padding: .5rem; line-height: 1.2; font-family: "Go Mono", Menlo, Consolas, monospace; font-size: 14px; }
+</style>
+</head>
+<body>
+<nav>
+<h1>{{.Command}}</h1>
+<ol>
+{{- range .Frames}}
+<li data-frame="{{.Index}}"><span class="{{if .Passed}}pass{{else}}fail{{end}}">{{if .Passed}}&#10003;{{else}}&#10007;{{end}}</span> {{.Title}} <span class="ms">{{.ElapsedMs}}ms</span></li>
+{{- end}}
+</ol>
+</nav>
+<main>
+<p>Generated {{.Generated}} &middot; <strong class="{{if .Passed}}pass{{else}}fail{{end}}">{{if .Passed}}PASSED{{else}}FAILED{{end}}</strong></p>
+{{- with .Timing}}
+<table>
+<tr><th>Total</th><td>{{.TotalMs}}ms</td></tr>
+<tr><th>Delay</th><td>{{.DelayMs}}ms</td></tr>
+{{- if .WaitForTextMs}}<tr><th>Wait for text</th><td>{{.WaitForTextMs}}ms</td></tr>{{end}}
+{{- if .StabilizeMs}}<tr><th>Stabilize</th><td>{{.StabilizeMs}}ms</td></tr>{{end}}
+{{- if .KeysMs}}<tr><th>Keys</th><td>{{.KeysMs}}ms</td></tr>{{end}}
+{{- if .ExpectMs}}<tr><th>Expect</th><td>{{.ExpectMs}}ms</td></tr>{{end}}
+</table>
+{{- end}}
+{{- if .Cases}}
+<table>
+<tr><th></th><th>Expectation</th><th>Type</th><th>Message</th></tr>
+{{- range .Cases}}
+<tr><td class="{{if .Passed}}pass{{else}}fail{{end}}">{{if .Passed}}&#10003;{{else}}&#10007;{{end}}</td><td>{{.Name}}</td><td>{{.Class}}</td><td>{{.Message}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+<div class="controls">
+<button id="prev" title="Previous (&larr;)">&larr; Prev</button>
+<span id="position"></span>
+<button id="next" title="Next (&rarr;)">Next &rarr;</button>
+</div>
+{{- range .Frames}}
+<section class="frame" id="frame-{{.Index}}">
+<h2>{{.Title}}</h2>
+<p>{{if .Wait}}Wait: {{.Wait}}{{if .WaitMs}} ({{.WaitMs}}ms){{end}} &middot; {{end}}Elapsed: {{.ElapsedMs}}ms{{if not .Stable}} &middot; <span class="fail">still changing</span>{{end}}</p>
+{{.Screen}}
+{{- if or .Checks .Assertions}}
+<table>
+{{- range .Assertions}}
+<tr><td class="{{if .Passed}}pass{{else}}fail{{end}}">{{if .Passed}}&#10003;{{else}}&#10007;{{end}}</td><td>{{.Kind}}</td><td>{{.Text}}</td><td>{{.Message}}</td></tr>
+{{- end}}
+{{- range $text, $found := .Checks}}
+<tr><td class="{{if $found}}pass{{else}}fail{{end}}">{{if $found}}&#10003;{{else}}&#10007;{{end}}</td><td>check</td><td>{{$text}}</td><td></td></tr>
+{{- end}}
+</table>
+{{- end}}
+</section>
+{{- end}}
+</main>
+<script>
+(function () {
+  var frames = document.querySelectorAll(".frame");
+  var items = document.querySelectorAll("nav li");
+  var current = 0;
+  function show(i) {
+    if (i < 0 || i >= frames.length) return;
+    frames[current].classList.remove("current");
+    items[current].classList.remove("current");
+    current = i;
+    frames[current].classList.add("current");
+    items[current].classList.add("current");
+    document.getElementById("position").textContent = (current + 1) + " / " + frames.length;
+  }
+  items.forEach(function (li) {
+    li.addEventListener("click", function () { show(Number(li.dataset.frame)); });
+  });
+  document.getElementById("prev").addEventListener("click", function () { show(current - 1); });
+  document.getElementById("next").addEventListener("click", function () { show(current + 1); });
+  document.addEventListener("keydown", function (e) {
+    if (e.key === "ArrowLeft") show(current - 1);
+    if (e.key === "ArrowRight") show(current + 1);
+  });
+  frames[0].classList.add("current");
+  items[0].classList.add("current");
+  show(0);
+})();
+</script>
+</body>
+</html>
+`))
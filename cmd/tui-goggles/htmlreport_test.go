@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteHTMLReport(t *testing.T) {
+	capture := testCapture(40, []string{"<script>alert(1)</script>", "Status: ready"})
+	capture.view = capture.snap
+	capture.Assertions = []AssertionResult{
+		{Kind: assertContains, Text: "Saved", Passed: false, Message: `text "Saved" not found`},
+	}
+	suite := newTestSuite("./app <b>")
+	suite.add(testCase{name: `contains "Saved"`, class: "assert", message: `text "Saved" not found`})
+	suite.add(testCase{name: `check "ready"`, class: "check", passed: true})
+	timing := &TimingInfo{TotalMs: 900, DelayMs: 100, KeysMs: 200, ExpectMs: 345}
+
+	path := filepath.Join(t.TempDir(), "report.html")
+	if err := writeHTMLReport(path, capture, nil, config{keys: "enter"}, timing, suite); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := string(data)
+
+	for _, want := range []string{
+		"<strong class=\"fail\">FAILED</strong>",
+		`text &#34;Saved&#34; not found`,
+		"<tr><th>Expect</th><td>345ms</td></tr>",
+		"<tr><th>Keys</th><td>200ms</td></tr>",
+		"&lt;script&gt;alert(1)&lt;/script&gt;",
+		"./app &lt;b&gt;",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("report lacks %s", want)
+		}
+	}
+	for _, unwanted := range []string{"<script>alert", "<b>"} {
+		if strings.Contains(page, unwanted) {
+			t.Errorf("report contains unescaped %s", unwanted)
+		}
+	}
+	if strings.Contains(page, "Wait for text") {
+		t.Error("report shows a timing that was not measured")
+	}
+}
+
+func TestWriteHTMLReportPassed(t *testing.T) {
+	capture := testCapture(10, []string{"ok"})
+	capture.view = capture.snap
+	suite := newTestSuite("./app")
+	suite.add(testCase{name: `contains "ok"`, class: "assert", passed: true})
+
+	path := filepath.Join(t.TempDir(), "report.html")
+	if err := writeHTMLReport(path, capture, nil, config{}, &TimingInfo{}, suite); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page := string(data); !strings.Contains(page, "PASSED") || strings.Contains(page, "FAILED") {
+		t.Error("passing run is not reported as PASSED")
+	}
+}
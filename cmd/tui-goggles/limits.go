@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// sizeUnits are the suffixes accepted by parseSize.
+var sizeUnits = map[string]uint64{
+	"":  1,
+	"K": 1 << 10,
+	"M": 1 << 20,
+	"G": 1 << 30,
+}
+
+// parseSize parses a byte count with an optional K, M or G suffix (powers
+// of 1024), e.g. "512M".
+func parseSize(s string) (uint64, error) {
+	upper := strings.ToUpper(strings.TrimSuffix(strings.ToUpper(s), "B"))
+	num, unit := upper, ""
+	if n := len(upper); n > 0 && (upper[n-1] < '0' || upper[n-1] > '9') {
+		num, unit = upper[:n-1], upper[n-1:]
+	}
+	mult, ok := sizeUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("invalid size %q: unknown unit %q (expected K, M or G)", s, unit)
+	}
+	n, err := strconv.ParseUint(num, 10, 64)
+	if err != nil || n == 0 {
+		return 0, fmt.Errorf("invalid size %q: expected a positive number with optional K, M or G", s)
+	}
+	return n * mult, nil
+}
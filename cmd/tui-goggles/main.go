@@ -9,12 +9,19 @@
 //	0 - Success (capture completed, all assertions passed if any)
 //	1 - General error (invalid arguments, command failed to start)
 //	2 - Timeout (operation exceeded timeout)
-//	3 - Assertion failed (text specified with -assert was not found)
-//	4 - Command error (the target command exited with non-zero status)
+//	3 - Assertion failed (one or more -assert* flags did not hold)
+//	4 - Command error (the target command's exit status did not match -expect-exit)
 //
 // Usage:
 //
 //	tui-goggles [flags] -- command [args...]
+//	tui-goggles mcp
+//	tui-goggles serve [-listen addr]
+//	tui-goggles attach [-server url] SESSION_ID
+//
+// The mcp and serve commands keep terminal sessions alive between requests,
+// serving them as Model Context Protocol tools over stdin/stdout or over an
+// HTTP/JSON API. The attach command lets a human take over a serve session.
 //
 // Examples:
 //
@@ -45,13 +52,22 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
 	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 
+	"github.com/your-username/tui-goggles/internal/elements"
+	"github.com/your-username/tui-goggles/internal/render"
 	"github.com/your-username/tui-goggles/internal/terminal"
 )
 
@@ -74,15 +90,50 @@ type config struct {
 	keys          string
 	keysStdin     bool
 	outputFormat  string
+	frameDuration time.Duration
 	timeout       time.Duration
-	asserts       []string
+	asserts       []assertion
+	stepAsserts   []stepAssertion
+	seenAsserts   []seenAssertion
 	checks        []string
+	checksAt      []regionMatch
+	crop          *terminal.Rect
+	reports       []reportSpec
+	expectExit    int
 	captureEach   bool
+	diff          bool
+	lineNumbers   bool
+	showCursor    bool
+	highlight     bool
+	elements      bool
 	trim          bool
 	quiet         bool
 	waitStable    bool
 	outputFile    string
+	htmlReport    string
 	envVars       []string
+	cleanEnv      bool
+	envAllow      []string
+	cwd           string
+	stdinFile     string
+	stderrFile    string
+	extraFds      []string
+	shutdownKeys  string
+	shutdownWait  time.Duration
+	termWait      time.Duration
+	limits        terminal.Limits
+	traceFile     string
+	sandbox       terminal.Sandbox
+	expectRules   []terminal.ExpectRule
+	expectUntil   *regexp.Regexp
+	expectMax     int
+	expectIdle    time.Duration
+	mirror        bool
+	requireStable bool
+	recordFrames  bool
+	frames        bool
+	mask          terminal.Mask
+	maskOutput    bool
 	inputDelay    time.Duration
 }
 
@@ -99,6 +150,21 @@ func (a *arrayFlag) Set(value string) error {
 }
 
 func main() {
+	// When re-executed to set up -limit-* and sandbox options, this runs
+	// the target command instead of returning
+	terminal.RunSandboxHelper()
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "mcp":
+			os.Exit(runMCP(os.Args[2:]))
+		case "serve":
+			os.Exit(runServe(os.Args[2:]))
+		case "attach":
+			os.Exit(runAttach(os.Args[2:]))
+		}
+	}
+
 	cfg := parseFlags()
 
 	// Find command separator
@@ -121,7 +187,28 @@ func parseFlags() config {
 	cfg := config{}
 	var asserts arrayFlag
 	var checks arrayFlag
+	var assertsNot arrayFlag
+	var assertsRegex arrayFlag
+	var assertsCount arrayFlag
+	var assertsAt arrayFlag
+	var assertsHighlighted arrayFlag
+	var assertsStep arrayFlag
+	var assertsSeen arrayFlag
+	var assertsNotSeen arrayFlag
+	var checksAt arrayFlag
+	var crop string
+	var reports arrayFlag
 	var envVars arrayFlag
+	var envAllow arrayFlag
+	var extraFds arrayFlag
+	var memoryLimit string
+	var readOnly arrayFlag
+	var sandbox bool
+	var expectRules arrayFlag
+	var expectLineRules arrayFlag
+	var expectUntil string
+	var ignoreRegions arrayFlag
+	var ignorePatterns arrayFlag
 
 	flag.IntVar(&cfg.cols, "cols", 80, "Terminal width in columns")
 	flag.IntVar(&cfg.rows, "rows", 24, "Terminal height in rows")
@@ -131,38 +218,318 @@ func parseFlags() config {
 	flag.StringVar(&cfg.waitForText, "wait-for", "", "Wait for this text to appear before capturing")
 	flag.StringVar(&cfg.keys, "keys", "", "Keys to send (space-separated: 'down down enter' or literal: 'hello')")
 	flag.BoolVar(&cfg.keysStdin, "keys-stdin", false, "Read keys from stdin (one per line)")
-	flag.StringVar(&cfg.outputFormat, "format", "text", "Output format: text, json")
+	flag.StringVar(&cfg.outputFormat, "format", "text", "Output format: text, json, compact, svg, png, gif")
+	flag.DurationVar(&cfg.frameDuration, "frame-duration", time.Second, "How long each capture is shown in -format gif")
 	flag.DurationVar(&cfg.timeout, "timeout", 30*time.Second, "Overall timeout for the operation")
 	flag.Var(&asserts, "assert", "Assert this text appears on screen (can be specified multiple times, exit code 3 if not found)")
 	flag.Var(&checks, "check", "Check if text appears on screen (adds to 'checks' object in JSON output, no exit code change)")
+	flag.Var(&assertsNot, "assert-not", "Assert this text does NOT appear on screen (repeatable, exit code 3 if found)")
+	flag.Var(&assertsRegex, "assert-regex", "Assert this regular expression matches the screen (repeatable, exit code 3 if no match)")
+	flag.Var(&assertsCount, "assert-count", "Assert text appears exactly N times (format: text=N, repeatable, exit code 3 on mismatch)")
+	flag.Var(&assertsAt, "assert-at", "Assert text appears in a region (format: row,col,width[,height]:text, repeatable, exit code 3 if not found)")
+	flag.Var(&assertsHighlighted, "assert-highlighted", "Assert text appears inside a reverse-video (selected) run (repeatable, exit code 3 if not found)")
+	flag.Var(&assertsStep, "assert-step", "With -capture-each, assert against the capture after key N (format: N:text or N:kind:text, kind is not, regex, count, at or highlighted)")
+	flag.Var(&assertsSeen, "assert-seen", "Assert text appeared in any recorded frame, even briefly (format: text, A:text or A-B:text for key steps A to B; implies -record-frames)")
+	flag.Var(&assertsNotSeen, "assert-not-seen", "Assert text never appeared in any recorded frame, not even briefly (format as for -assert-seen)")
+	flag.BoolVar(&cfg.recordFrames, "record-frames", false, "Snapshot the screen after every output batch and include the distinct frames in JSON output")
+	flag.BoolVar(&cfg.frames, "frames", false, "Output a timeline of every distinct screen with its time, duration and preceding key (implies -record-frames; JSON and text formats)")
+	flag.Var(&checksAt, "check-at", "Check if text appears in a region (format: row,col,width[,height]:text, adds to 'checks' in JSON output)")
+	flag.StringVar(&crop, "crop", "", "Capture only this rectangle of the screen (format: row,col,width,height)")
+	flag.Var(&reports, "report", "Write a test report (format: junit[=path] or tap[=path], stderr if no path, repeatable)")
+	flag.IntVar(&cfg.expectExit, "expect-exit", -1, "Expect the command to exit with this status (exit code 4 otherwise, -1 to skip)")
 	flag.BoolVar(&cfg.captureEach, "capture-each", false, "Capture screen after each key (returns array in JSON mode)")
+	flag.BoolVar(&cfg.diff, "diff", false, "In text mode with -capture-each, show only changed lines after the first capture")
+	flag.BoolVar(&cfg.lineNumbers, "line-numbers", false, "Prefix each line with its row number in compact output")
+	flag.BoolVar(&cfg.showCursor, "show-cursor", false, "Mark the cursor position in the screen text with "+cursorMarker)
+	flag.BoolVar(&cfg.highlight, "highlight", false, "Bracket reverse-video (selected) cell runs with "+highlightStart+" and "+highlightEnd)
+	flag.BoolVar(&cfg.elements, "elements", false, "Detect panels, lists, status/tab bars and input fields (adds 'elements' to JSON output)")
 	flag.BoolVar(&cfg.trim, "trim", false, "Trim trailing blank lines from output")
 	flag.BoolVar(&cfg.quiet, "quiet", false, "Suppress output on success (useful with -assert)")
 	flag.BoolVar(&cfg.waitStable, "wait-stable", false, "Wait for screen to stabilize before capturing")
+	flag.BoolVar(&cfg.requireStable, "require-stable", false, "Fail (exit code 2) if the screen was still changing when it was captured")
 	flag.StringVar(&cfg.outputFile, "output", "", "Write output to file instead of stdout")
+	flag.StringVar(&cfg.htmlReport, "html", "", "Also write a self-contained HTML report with every step, screen, timing and assertion to this file")
 	flag.Var(&envVars, "env", "Set environment variable for command (format: KEY=VALUE, can be repeated)")
+	flag.BoolVar(&cfg.cleanEnv, "clean-env", false, "Start the command in a minimal fixed environment (LANG=C.UTF-8, TZ=UTC, temporary HOME, fixed PATH) instead of inheriting ours")
+	flag.Var(&envAllow, "env-allow", "With -clean-env, pass this variable through from our environment (repeatable)")
+	flag.StringVar(&cfg.cwd, "cwd", "", "Run the command in this working directory")
+	flag.StringVar(&cfg.stdinFile, "stdin-file", "", "Give the command this file as standard input instead of the PTY ('-' for our stdin)")
+	flag.StringVar(&cfg.stderrFile, "stderr-file", "", "Write the command's standard error to this file instead of the screen")
+	flag.Var(&extraFds, "extra-fd", "Pass a file or one of our descriptors (number) to the command as fd 3, 4, ...: a path is truncated for writing, >>path appends and <path is read (repeatable)")
+	flag.StringVar(&cfg.shutdownKeys, "shutdown-keys", "", "Keys to send to ask the app to quit before signalling it (e.g. 'q' or 'ctrl-c')")
+	flag.DurationVar(&cfg.shutdownWait, "shutdown-wait", time.Second, "How long to wait for the app to exit after -shutdown-keys")
+	flag.DurationVar(&cfg.termWait, "term-wait", time.Second, "How long to wait after SIGTERM before SIGKILL")
+	flag.DurationVar(&cfg.limits.CPU, "limit-cpu", 0, "Limit the command's CPU time (e.g. 10s; it gets SIGXCPU, then SIGKILL a second later)")
+	flag.StringVar(&memoryLimit, "limit-memory", "", "Limit the command's address space (e.g. 512M)")
+	flag.Uint64Var(&cfg.limits.OpenFiles, "limit-files", 0, "Limit the number of files the command can open")
+	flag.Uint64Var(&cfg.limits.Processes, "limit-procs", 0, "Limit the number of processes for the user (not enforced for root outside -sandbox)")
+	flag.BoolVar(&cfg.sandbox.NoNetwork, "no-network", false, "Run the command without network access (Linux network namespace)")
+	flag.BoolVar(&cfg.sandbox.PrivateTmp, "private-tmp", false, "Give the command an empty private /tmp (Linux mount namespace)")
+	flag.Var(&readOnly, "read-only", "Make this path read-only for the command (Linux mount namespace, repeatable)")
+	flag.BoolVar(&sandbox, "sandbox", false, "Shorthand for -no-network -private-tmp -read-only <working directory>")
+	flag.BoolVar(&cfg.mirror, "mirror", false, "Draw the virtual screen in this terminal as it updates, to watch the run")
+	flag.StringVar(&cfg.traceFile, "trace", "", "Write a JSON-lines log of output read, queries answered, keys sent and waits to this file")
+	flag.Var(&expectRules, "expect", "When the screen matches a regular expression, send keys (format: PATTERN=>KEYS, repeatable, first match wins)")
+	flag.Var(&expectLineRules, "expect-line", "Like -expect, but match only the last non-blank line (format: PATTERN=>KEYS, repeatable)")
+	flag.StringVar(&expectUntil, "expect-until", "", "Keep applying -expect rules until this regular expression matches the screen")
+	flag.IntVar(&cfg.expectMax, "expect-max", 100, "Fail (exit code 2) if -expect rules fire more than this many times")
+	flag.DurationVar(&cfg.expectIdle, "expect-idle", time.Second, "Without -expect-until, stop applying -expect rules once none matches and the screen has not changed for this long")
+	flag.Var(&ignoreRegions, "ignore-region", "Ignore this rectangle (e.g. a clock) when waiting for a stable screen and diffing (format: row,col[,width[,height]], repeatable)")
+	flag.Var(&ignorePatterns, "ignore-pattern", "Ignore text matching this regular expression when waiting for a stable screen and diffing (repeatable)")
+	flag.BoolVar(&cfg.maskOutput, "mask-output", false, "Also replace the ignored cells with "+string(terminal.MaskRune)+" in the output, for comparing against saved screens")
 	flag.DurationVar(&cfg.inputDelay, "input-delay", 50*time.Millisecond, "Delay between keystrokes")
 
 	flag.Parse()
 
-	cfg.asserts = asserts
+	for _, text := range asserts {
+		cfg.asserts = append(cfg.asserts, assertion{kind: assertContains, text: text})
+	}
+	for _, text := range assertsNot {
+		cfg.asserts = append(cfg.asserts, assertion{kind: assertNotContains, text: text})
+	}
+	for _, pattern := range assertsRegex {
+		a, err := parseRegexAssertion(pattern)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -assert-regex: %v\n", err)
+			os.Exit(ExitGeneralError)
+		}
+		cfg.asserts = append(cfg.asserts, a)
+	}
+	for _, spec := range assertsCount {
+		a, err := parseCountAssertion(spec)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -assert-count: %v\n", err)
+			os.Exit(ExitGeneralError)
+		}
+		cfg.asserts = append(cfg.asserts, a)
+	}
 	cfg.checks = checks
 	cfg.envVars = envVars
+	cfg.envAllow = envAllow
+	cfg.extraFds = extraFds
+	if cfg.cwd != "" {
+		if info, err := os.Stat(cfg.cwd); err != nil || !info.IsDir() {
+			fmt.Fprintf(os.Stderr, "Error: -cwd: %q is not a directory\n", cfg.cwd)
+			os.Exit(ExitGeneralError)
+		}
+	}
+	if memoryLimit != "" {
+		size, err := parseSize(memoryLimit)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -limit-memory: %v\n", err)
+			os.Exit(ExitGeneralError)
+		}
+		cfg.limits.Memory = size
+	}
+	if sandbox {
+		cfg.sandbox.NoNetwork = true
+		cfg.sandbox.PrivateTmp = true
+		dir := cfg.cwd
+		if dir == "" {
+			dir, _ = os.Getwd()
+		}
+		readOnly = append(readOnly, dir)
+	}
+	for _, path := range readOnly {
+		abs, err := filepath.Abs(path)
+		if err == nil {
+			_, err = os.Stat(abs)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -read-only: %v\n", err)
+			os.Exit(ExitGeneralError)
+		}
+		cfg.sandbox.ReadOnly = append(cfg.sandbox.ReadOnly, abs)
+	}
+	if cfg.stdinFile == "-" && cfg.keysStdin {
+		fmt.Fprintln(os.Stderr, "Error: -stdin-file - cannot be combined with -keys-stdin")
+		os.Exit(ExitGeneralError)
+	}
+	if len(cfg.envAllow) > 0 && !cfg.cleanEnv {
+		fmt.Fprintln(os.Stderr, "Error: -env-allow requires -clean-env")
+		os.Exit(ExitGeneralError)
+	}
+
+	for _, spec := range assertsAt {
+		m, err := parseRegionMatch(spec)
+		if err == nil {
+			err = checkOnScreen(m.rect, cfg.cols, cfg.rows)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -assert-at: %v\n", err)
+			os.Exit(ExitGeneralError)
+		}
+		cfg.asserts = append(cfg.asserts, assertion{kind: assertRegion, text: m.text, region: &m})
+	}
+	for _, text := range assertsHighlighted {
+		cfg.asserts = append(cfg.asserts, assertion{kind: assertHighlighted, text: text})
+	}
+	for _, spec := range assertsStep {
+		a, err := parseStepAssertion(spec)
+		if err == nil && a.region != nil {
+			err = checkOnScreen(a.region.rect, cfg.cols, cfg.rows)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -assert-step: %v\n", err)
+			os.Exit(ExitGeneralError)
+		}
+		cfg.stepAsserts = append(cfg.stepAsserts, a)
+	}
+	if len(cfg.stepAsserts) > 0 && !cfg.captureEach {
+		fmt.Fprintln(os.Stderr, "Error: -assert-step requires -capture-each")
+		os.Exit(ExitGeneralError)
+	}
+	for _, spec := range assertsSeen {
+		a, err := parseSeenAssertion(assertSeen, spec)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -assert-seen: %v\n", err)
+			os.Exit(ExitGeneralError)
+		}
+		cfg.seenAsserts = append(cfg.seenAsserts, a)
+	}
+	for _, spec := range assertsNotSeen {
+		a, err := parseSeenAssertion(assertNotSeen, spec)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -assert-not-seen: %v\n", err)
+			os.Exit(ExitGeneralError)
+		}
+		cfg.seenAsserts = append(cfg.seenAsserts, a)
+	}
+	// Recorded frames keep only the text, so they cannot be animated
+	if cfg.recordFrames && cfg.outputFormat == "gif" {
+		fmt.Fprintln(os.Stderr, "Error: -format gif cannot animate -record-frames (frames keep only their text); use -capture-each to animate one capture per key")
+		os.Exit(ExitGeneralError)
+	}
+	if len(cfg.seenAsserts) > 0 || cfg.frames {
+		cfg.recordFrames = true
+	}
+	for _, spec := range checksAt {
+		m, err := parseRegionMatch(spec)
+		if err == nil {
+			err = checkOnScreen(m.rect, cfg.cols, cfg.rows)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -check-at: %v\n", err)
+			os.Exit(ExitGeneralError)
+		}
+		cfg.checksAt = append(cfg.checksAt, m)
+	}
+	for _, spec := range reports {
+		r, err := parseReportSpec(spec)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -report: %v\n", err)
+			os.Exit(ExitGeneralError)
+		}
+		cfg.reports = append(cfg.reports, r)
+	}
+	for _, spec := range expectRules {
+		rule, err := parseExpectRule(spec, false)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -expect: %v\n", err)
+			os.Exit(ExitGeneralError)
+		}
+		cfg.expectRules = append(cfg.expectRules, rule)
+	}
+	for _, spec := range expectLineRules {
+		rule, err := parseExpectRule(spec, true)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -expect-line: %v\n", err)
+			os.Exit(ExitGeneralError)
+		}
+		cfg.expectRules = append(cfg.expectRules, rule)
+	}
+	if expectUntil != "" {
+		re, err := regexp.Compile(expectUntil)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -expect-until: %v\n", err)
+			os.Exit(ExitGeneralError)
+		}
+		cfg.expectUntil = re
+	}
+	if cfg.expectMax < 1 {
+		fmt.Fprintln(os.Stderr, "Error: -expect-max must be at least 1")
+		os.Exit(ExitGeneralError)
+	}
+	for _, spec := range ignoreRegions {
+		rect, err := parseRect(spec, false)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -ignore-region: invalid rectangle %q: %v\n", spec, err)
+			os.Exit(ExitGeneralError)
+		}
+		cfg.mask.Regions = append(cfg.mask.Regions, rect)
+	}
+	for _, pattern := range ignorePatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -ignore-pattern: %v\n", err)
+			os.Exit(ExitGeneralError)
+		}
+		cfg.mask.Patterns = append(cfg.mask.Patterns, re)
+	}
+	if cfg.maskOutput && cfg.mask.Empty() {
+		fmt.Fprintln(os.Stderr, "Error: -mask-output requires -ignore-region or -ignore-pattern")
+		os.Exit(ExitGeneralError)
+	}
+	if cfg.frameDuration <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: -frame-duration must be positive")
+		os.Exit(ExitGeneralError)
+	}
+	if crop != "" {
+		rect, err := parseRect(crop, true)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -crop: invalid rectangle %q: %v\n", crop, err)
+			os.Exit(ExitGeneralError)
+		}
+		cfg.crop = &rect
+	}
 	return cfg
 }
 
 // CaptureResult contains the captured screenshot and metadata.
 type CaptureResult struct {
-	Screen        string          `json:"screen"`
-	Cols          int             `json:"cols"`
-	Rows          int             `json:"rows"`
-	CursorRow     int             `json:"cursor_row"`
-	CursorCol     int             `json:"cursor_col"`
-	CursorVisible bool            `json:"cursor_visible"`
-	Timestamp     time.Time       `json:"timestamp"`
-	Command       string          `json:"command"`
-	Checks        map[string]bool `json:"checks,omitempty"`
-	Timing        *TimingInfo     `json:"timing,omitempty"`
+	Screen        string                   `json:"screen"`
+	Cols          int                      `json:"cols"`
+	Rows          int                      `json:"rows"`
+	CursorRow     int                      `json:"cursor_row"`
+	CursorCol     int                      `json:"cursor_col"`
+	CursorVisible bool                     `json:"cursor_visible"`
+	Timestamp     time.Time                `json:"timestamp"`
+	Stable        bool                     `json:"stable"`
+	SinceChangeMs int64                    `json:"since_change_ms"`
+	Command       string                   `json:"command"`
+	Checks        map[string]bool          `json:"checks,omitempty"`
+	Timing        *TimingInfo              `json:"timing,omitempty"`
+	Step          *StepInfo                `json:"step,omitempty"`
+	Diff          *ScreenDiff              `json:"diff,omitempty"`
+	Elements      []elements.Element       `json:"elements,omitempty"`
+	Crop          *terminal.Rect           `json:"crop,omitempty"`
+	Assertions    []AssertionResult        `json:"assertions,omitempty"`
+	Env           map[string]string        `json:"env,omitempty"`
+	Shutdown      *terminal.ShutdownResult `json:"shutdown,omitempty"`
+	Limits        *terminal.LimitReport    `json:"limits,omitempty"`
+	Expect        *terminal.ExpectResult   `json:"expect,omitempty"`
+	Frames        []FrameInfo              `json:"frames,omitempty"`
+
+	// plainScreen is the screen without cursor or highlight markers. Checks
+	// and assertions are evaluated against it.
+	plainScreen string
+	// compareScreen is plainScreen with the -ignore-* parts masked; diffs
+	// are evaluated against it.
+	compareScreen string
+	// snap is the full, uncropped grid; region assertions are evaluated
+	// against it.
+	snap terminal.Snapshot
+	// view is the captured (possibly cropped) grid that image formats render.
+	view terminal.Snapshot
+}
+
+// StepInfo describes what led up to a capture in -capture-each mode.
+type StepInfo struct {
+	Index     int    `json:"index"`
+	Key       string `json:"key,omitempty"`
+	Wait      string `json:"wait,omitempty"`
+	WaitMs    int64  `json:"wait_ms"`
+	ElapsedMs int64  `json:"elapsed_ms"`
 }
 
 // TimingInfo contains timing information about the capture.
@@ -172,6 +539,10 @@ type TimingInfo struct {
 	StabilizeMs   int64 `json:"stabilize_ms,omitempty"`
 	WaitForTextMs int64 `json:"wait_for_text_ms,omitempty"`
 	KeysMs        int64 `json:"keys_ms,omitempty"`
+	ExpectMs      int64 `json:"expect_ms,omitempty"`
+	// Stable reports whether the screen settled before the capture; nil if
+	// stability was not waited for.
+	Stable *bool `json:"stable,omitempty"`
 }
 
 // MultiCaptureResult contains multiple captures (for -capture-each mode).
@@ -179,17 +550,28 @@ type MultiCaptureResult struct {
 	Captures []CaptureResult `json:"captures"`
 	Command  string          `json:"command"`
 	Timing   *TimingInfo     `json:"timing,omitempty"`
+	// Assertions are evaluated against the final capture.
+	Assertions []AssertionResult        `json:"assertions,omitempty"`
+	Env        map[string]string        `json:"env,omitempty"`
+	Shutdown   *terminal.ShutdownResult `json:"shutdown,omitempty"`
+	Limits     *terminal.LimitReport    `json:"limits,omitempty"`
+	Expect     *terminal.ExpectResult   `json:"expect,omitempty"`
+	Frames     []FrameInfo              `json:"frames,omitempty"`
 }
 
 func run(command string, args []string, cfg config) int {
 	startTime := time.Now()
 	timing := &TimingInfo{}
 
+	// Our messages go to stderr, unless -mirror holds them back until the
+	// run is over
+	stderr := io.Writer(os.Stderr)
+
 	// Read keys from stdin if requested
 	if cfg.keysStdin {
 		keys, err := readKeysFromStdin()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: reading keys from stdin: %v\n", err)
+			fmt.Fprintf(stderr, "Error: reading keys from stdin: %v\n", err)
 			return ExitGeneralError
 		}
 		if cfg.keys != "" {
@@ -204,22 +586,102 @@ func run(command string, args []string, cfg config) int {
 		Rows: cfg.rows,
 		Cols: cfg.cols,
 		Env:  cfg.envVars,
+		Dir:  cfg.cwd,
+
+		Limits:     cfg.limits,
+		Sandbox:    cfg.sandbox,
+		StableMask: cfg.mask,
+
+		RecordFrames: cfg.recordFrames,
+	}
+	if cfg.traceFile != "" {
+		traceFile, err := os.Create(cfg.traceFile)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: -trace: %v\n", err)
+			return ExitGeneralError
+		}
+		defer traceFile.Close()
+		termOpts.Trace = traceFile
+	}
+	closeFiles, err := openChildFiles(cfg, &termOpts)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return ExitGeneralError
+	}
+	defer closeFiles()
+	if cfg.cleanEnv {
+		home, err := os.MkdirTemp("", "tui-goggles-home-")
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: creating temporary HOME: %v\n", err)
+			return ExitGeneralError
+		}
+		defer os.RemoveAll(home)
+		termOpts.CleanEnv = true
+		termOpts.Env = append(cleanEnv(home, cfg.cols, cfg.rows, cfg.envAllow), cfg.envVars...)
 	}
 
 	term, err := terminal.New(command, args, termOpts)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: failed to create terminal: %v\n", err)
+		fmt.Fprintf(stderr, "Error: failed to create terminal: %v\n", err)
 		return ExitGeneralError
 	}
+	stopMirror := func() {}
 	defer term.Close()
 
-	// Set up overall timeout
-	timedOut := false
+	// Watch the run in our own terminal; stopped before the output is
+	// written so the output ends up below the last frame
+	if cfg.mirror {
+		m, err := startMirror(term)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return ExitGeneralError
+		}
+		defer m.close()
+		stopMirror = m.close
+		stderr = m.stderr
+	}
+
+	// Collect expectations for -report; written once the run is finished
+	suite := newTestSuite(strings.TrimSpace(command + " " + strings.Join(args, " ")))
+	if len(cfg.reports) > 0 {
+		defer writeReports(cfg.reports, suite, stderr)
+	}
+
+	// The HTML report is written from a defer too, so runs that end early
+	// get one; it then shows the screen as it was when the run stopped
+	var results []CaptureResult
+	var finalResult CaptureResult
+	captured := false
+	if cfg.htmlReport != "" {
+		defer func() {
+			if !captured {
+				timing.TotalMs = time.Since(startTime).Milliseconds()
+				finalResult = captureScreen(term, command, args, cfg, timing)
+			}
+			if err := writeHTMLReport(cfg.htmlReport, finalResult, results, cfg, timing, suite); err != nil {
+				fmt.Fprintf(stderr, "Error: writing HTML report to %q: %v\n", cfg.htmlReport, err)
+			}
+		}()
+	}
+
+	// Set up overall timeout; on timeout or when we are signalled, the
+	// command's whole process group is killed and the run winds down from
+	// there, so reports are still written and temporary files removed. A
+	// second signal is not caught and ends us at once.
+	var timedOut, interrupted atomic.Bool
 	done := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
 	go func() {
 		select {
 		case <-time.After(cfg.timeout):
-			timedOut = true
+			timedOut.Store(true)
+			term.Close()
+		case sig := <-signals:
+			signal.Stop(signals)
+			interrupted.Store(true)
+			fmt.Fprintf(stderr, "Error: received %v, killing command\n", sig)
 			term.Close()
 		case <-done:
 		}
@@ -228,7 +690,7 @@ func run(command string, args []string, cfg config) int {
 
 	// Initial delay to let the TUI render
 	delayStart := time.Now()
-	time.Sleep(cfg.delay)
+	term.Delay(cfg.delay)
 	timing.DelayMs = time.Since(delayStart).Milliseconds()
 
 	// Wait for specific text if requested
@@ -236,33 +698,72 @@ func run(command string, args []string, cfg config) int {
 		waitStart := time.Now()
 		err := term.WaitForText(cfg.waitForText, cfg.stableTimeout)
 		timing.WaitForTextMs = time.Since(waitStart).Milliseconds()
+		suite.add(testCase{
+			name:     fmt.Sprintf("wait-for %q", cfg.waitForText),
+			class:    "wait",
+			passed:   err == nil,
+			message:  errorMessage(err),
+			duration: time.Since(waitStart),
+		})
 		if err != nil {
-			if timedOut {
-				fmt.Fprintf(os.Stderr, "Error: timeout waiting for text %q\n", cfg.waitForText)
+			suite.setScreen(term.Screenshot())
+			if timedOut.Load() {
+				fmt.Fprintf(stderr, "Error: timeout waiting for text %q\n", cfg.waitForText)
 				return ExitTimeout
 			}
-			fmt.Fprintf(os.Stderr, "Error: waiting for text %q: %v\n", cfg.waitForText, err)
+			fmt.Fprintf(stderr, "Error: waiting for text %q: %v\n", cfg.waitForText, err)
 			return ExitGeneralError
 		}
 	}
 
+	// Key steps are logged so recorded frames can be attributed to them
+	var steps *stepLog
+	if cfg.recordFrames {
+		steps = &stepLog{}
+	}
+
+	// stableErr is the outcome of the last wait for a stable screen, and
+	// settled whether nothing was sent to the app since; a capture taken
+	// without such a wait is stable if there was no output for -stable-time
+	var stableErr error
+	settled := false
+
 	// Wait for stable screen if requested (before any keys)
 	if cfg.waitStable {
 		stabilizeStart := time.Now()
-		_ = term.WaitForStable(cfg.stableTimeout, cfg.stableTime)
+		stableErr = term.WaitForStable(cfg.stableTimeout, cfg.stableTime)
+		settled = true
 		timing.StabilizeMs = time.Since(stabilizeStart).Milliseconds()
+		stable := stableErr == nil
+		timing.Stable = &stable
+		suite.add(testCase{
+			name:     "wait-stable",
+			class:    "wait",
+			passed:   stableErr == nil,
+			message:  errorMessage(stableErr),
+			duration: time.Since(stabilizeStart),
+		})
 	}
 
-	var results []CaptureResult
-
 	// Capture initial state if capture-each mode
 	if cfg.captureEach {
-		results = append(results, captureScreen(term, command, args, cfg, nil))
+		initial := captureScreen(term, command, args, cfg, nil)
+		if settled {
+			initial.Stable = stableErr == nil
+		}
+		initial.Step = &StepInfo{
+			Index:     0,
+			Wait:      initialWait(cfg),
+			WaitMs:    time.Since(delayStart).Milliseconds(),
+			ElapsedMs: time.Since(startTime).Milliseconds(),
+		}
+		results = append(results, initial)
 	}
 
 	// Send keys if specified
 	if cfg.keys != "" {
 		keysStart := time.Now()
+		settled = false
 		if cfg.captureEach {
 			// Send keys one at a time and capture after each
 			parts := strings.Split(cfg.keys, " ")
@@ -271,38 +772,97 @@ func run(command string, args []string, cfg config) int {
 					continue
 				}
 				key := parseKey(part)
+				steps.mark(part)
 				if err := term.SendKeys(string(key)); err != nil {
-					fmt.Fprintf(os.Stderr, "Error: sending key %q: %v\n", part, err)
+					fmt.Fprintf(stderr, "Error: sending key %q: %v\n", part, err)
 					return ExitGeneralError
 				}
 				// Wait for screen to stabilize after key input
-				time.Sleep(cfg.inputDelay)
-				_ = term.WaitForStable(cfg.stableTimeout, cfg.stableTime)
-				results = append(results, captureScreen(term, command, args, cfg, nil))
+				waitStart := time.Now()
+				term.Delay(cfg.inputDelay)
+				err := term.WaitForStable(cfg.stableTimeout, cfg.stableTime)
+				capture := captureScreen(term, command, args, cfg, nil)
+				capture.Stable = err == nil
+				capture.Step = &StepInfo{
+					Index:     len(results),
+					Key:       part,
+					Wait:      "stable",
+					WaitMs:    time.Since(waitStart).Milliseconds(),
+					ElapsedMs: time.Since(startTime).Milliseconds(),
+				}
+				results = append(results, capture)
 			}
 		} else {
 			// Send all keys, then capture once
-			if err := sendKeys(term, cfg.keys, cfg.inputDelay); err != nil {
-				fmt.Fprintf(os.Stderr, "Error: sending keys: %v\n", err)
+			if err := sendKeys(term, cfg.keys, cfg.inputDelay, steps); err != nil {
+				fmt.Fprintf(stderr, "Error: sending keys: %v\n", err)
 				return ExitGeneralError
 			}
 			// Wait for screen to stabilize after key input
-			time.Sleep(cfg.stableTime)
+			term.Delay(cfg.stableTime)
 		}
 		timing.KeysMs = time.Since(keysStart).Milliseconds()
 	}
 
-	// Wait for stable screen (ignore timeout - just capture current state)
+	// Answer prompts with -expect rules until they are done
+	var expectResult *terminal.ExpectResult
+	expectPassed := true
+	if len(cfg.expectRules) > 0 || cfg.expectUntil != nil {
+		expectStart := time.Now()
+		result := term.Expect(cfg.expectRules, terminal.ExpectOptions{
+			Until:         cfg.expectUntil,
+			MaxIterations: cfg.expectMax,
+			Idle:          cfg.expectIdle,
+			Quiet:         cfg.stableTime,
+			Timeout:       time.Until(startTime.Add(cfg.timeout)),
+		})
+		timing.ExpectMs = time.Since(expectStart).Milliseconds()
+		expectResult = &result
+		message := expectFailure(result, cfg.expectUntil != nil)
+		if message != "" {
+			expectPassed = false
+			fmt.Fprintf(stderr, "Error: %s\n", message)
+		}
+		suite.add(testCase{
+			name:     fmt.Sprintf("expect (%d rules)", len(cfg.expectRules)),
+			class:    "expect",
+			passed:   expectPassed,
+			message:  message,
+			duration: time.Since(expectStart),
+		})
+
+		if len(result.Fired) > 0 {
+			settled = false
+			steps.markAt("expect", expectStart)
+		}
+
+		if cfg.captureEach && len(result.Fired) > 0 {
+			capture := captureScreen(term, command, args, cfg, nil)
+			capture.Step = &StepInfo{
+				Index:     len(results),
+				Wait:      fmt.Sprintf("expect (%d fired)", len(result.Fired)),
+				WaitMs:    timing.ExpectMs,
+				ElapsedMs: time.Since(startTime).Milliseconds(),
+			}
+			results = append(results, capture)
+		}
+	}
+
+	// Wait for stable screen (a timeout is only reported - the current
+	// state is captured anyway)
 	if !cfg.waitStable {
 		stabilizeStart := time.Now()
-		_ = term.WaitForStable(cfg.stableTimeout, cfg.stableTime)
+		stableErr = term.WaitForStable(cfg.stableTimeout, cfg.stableTime)
+		settled = true
 		timing.StabilizeMs = time.Since(stabilizeStart).Milliseconds()
+		stable := stableErr == nil
+		timing.Stable = &stable
 	}
 
 	timing.TotalMs = time.Since(startTime).Milliseconds()
 
 	// Final capture (or only capture if not capture-each mode)
-	var finalResult CaptureResult
+	captured = true
 	if cfg.captureEach {
 		// Already captured, use last result
 		if len(results) > 0 {
@@ -310,49 +870,263 @@ func run(command string, args []string, cfg config) int {
 		}
 	} else {
 		finalResult = captureScreen(term, command, args, cfg, timing)
+		if settled {
+			finalResult.Stable = stableErr == nil
+		}
+	}
+
+	// Diff each capture against the one before it
+	for i := 1; i < len(results); i++ {
+		results[i].Diff = diffScreens(results[i-1].compareScreen, results[i].compareScreen)
+	}
+	if cfg.captureEach && len(results) > 0 {
+		finalResult = results[len(results)-1]
+	}
+
+	// Evaluate checks and assertions against every intermediate capture;
+	// step assertions only against the capture they are attached to
+	assertionsPassed := true
+	for i := range results {
+		results[i].Checks = evaluateChecks(cfg, results[i])
+		results[i].Assertions, _ = evaluateAssertions(cfg.asserts, results[i])
+
+		stepResults, passed := evaluateStepAssertions(cfg.stepAsserts, i, results[i])
+		results[i].Assertions = append(results[i].Assertions, stepResults...)
+		if !passed {
+			assertionsPassed = false
+		}
+		for _, r := range stepResults {
+			reportAssertion(suite, r, stderr)
+		}
+	}
+	missingSteps := missingStepResults(cfg.stepAsserts, len(results))
+	for _, r := range missingSteps {
+		assertionsPassed = false
+		reportAssertion(suite, r, stderr)
 	}
 
-	// Process checks (non-fatal text presence checks)
-	if len(cfg.checks) > 0 {
-		checksResult := make(map[string]bool)
-		screen := finalResult.Screen
+	// Process checks (non-fatal text presence checks) on the final screen
+	if len(cfg.checks) > 0 || len(cfg.checksAt) > 0 {
+		finalResult.Checks = evaluateChecks(cfg, finalResult)
 		for _, checkText := range cfg.checks {
-			checksResult[checkText] = strings.Contains(screen, checkText)
+			suite.add(checkCase(checkText, finalResult.Checks[checkText]))
 		}
-		finalResult.Checks = checksResult
-		// Update checks in all results if capture-each mode
-		for i := range results {
-			results[i].Checks = checksResult
+		for _, check := range cfg.checksAt {
+			suite.add(checkCase(check.spec, finalResult.Checks[check.spec]))
 		}
 	}
 
-	// Check assertions against final screen
+	// Evaluate every assertion against the final screen so all failures are
+	// reported at once
 	if len(cfg.asserts) > 0 {
-		screen := finalResult.Screen
-		for _, assertText := range cfg.asserts {
-			if !strings.Contains(screen, assertText) {
-				fmt.Fprintf(os.Stderr, "Assertion failed: text %q not found on screen\n", assertText)
-				// Still output the screen for debugging (unless quiet)
-				if !cfg.quiet {
-					outputResult(finalResult, results, cfg, timing)
-				}
-				return ExitAssertionFailed
+		var passed bool
+		finalResult.Assertions, passed = evaluateAssertions(cfg.asserts, finalResult)
+		if !passed {
+			assertionsPassed = false
+		}
+		for _, r := range finalResult.Assertions {
+			reportAssertion(suite, r, stderr)
+		}
+	}
+	finalResult.Assertions = append(finalResult.Assertions, missingSteps...)
+
+	// Check what appeared in between captures against the recorded frames
+	if cfg.recordFrames {
+		finalResult.Frames = frameInfos(term.Frames(), steps, startTime, time.Now(), cfg.trim)
+		for _, a := range cfg.seenAsserts {
+			r := a.evaluate(finalResult.Frames)
+			if !r.Passed {
+				assertionsPassed = false
 			}
+			finalResult.Assertions = append(finalResult.Assertions, r)
+			reportAssertion(suite, r, stderr)
+		}
+	}
+
+	// Check the command's exit status if an expectation was given
+	exitPassed := true
+	if cfg.expectExit >= 0 {
+		exitStart := time.Now()
+		message := ""
+		code, err := term.WaitExit(cfg.stableTimeout)
+		if err != nil {
+			exitPassed = false
+			message = fmt.Sprintf("command still running, expected exit status %d", cfg.expectExit)
+		} else if code != cfg.expectExit {
+			exitPassed = false
+			message = fmt.Sprintf("command exited with status %d, expected %d", code, cfg.expectExit)
+		}
+		if !exitPassed {
+			fmt.Fprintf(stderr, "Error: %s\n", message)
+		}
+		suite.add(testCase{
+			name:     fmt.Sprintf("exit status %d", cfg.expectExit),
+			class:    "exit",
+			passed:   exitPassed,
+			message:  message,
+			duration: time.Since(exitStart),
+		})
+	}
+
+	// With -require-stable, a capture of a screen that was still changing
+	// is a failure
+	stablePassed := true
+	if cfg.requireStable {
+		captures := results
+		if !cfg.captureEach {
+			captures = []CaptureResult{finalResult}
+		}
+		message := ""
+		for i, c := range captures {
+			if c.Stable {
+				continue
+			}
+			stablePassed = false
+			if cfg.captureEach {
+				message = fmt.Sprintf("screen was still changing at capture %d (last change %dms before)", i, c.SinceChangeMs)
+			} else {
+				message = fmt.Sprintf("screen was still changing when captured (last change %dms before)", c.SinceChangeMs)
+			}
+			fmt.Fprintf(stderr, "Error: %s\n", message)
+		}
+		suite.add(testCase{
+			name:    "stable screen",
+			class:   "wait",
+			passed:  stablePassed,
+			message: message,
+		})
+	}
+	suite.setScreen(finalResult.plainScreen)
+
+	// Record the effective environment when it is the fixed one (the
+	// inherited environment may hold secrets)
+	if cfg.cleanEnv {
+		finalResult.Env = envMap(term.Env())
+	}
+
+	// End the command: ask it to quit, then signal its process group
+	shutdown := term.Shutdown(terminal.ShutdownPolicy{
+		Keys:     keySequence(cfg.shutdownKeys),
+		KeysWait: cfg.shutdownWait,
+		TermWait: cfg.termWait,
+	})
+	finalResult.Shutdown = &shutdown
+	for _, p := range shutdown.Leftovers {
+		fmt.Fprintf(stderr, "Error: killed leftover process %d (%s)\n", p.PID, p.Command)
+	}
+	finalResult.Expect = expectResult
+	finalResult.Limits = term.LimitReport()
+	if finalResult.Limits != nil {
+		for _, limit := range finalResult.Limits.Breached {
+			fmt.Fprintf(stderr, "Error: command exceeded its %s limit\n", limit)
+		}
+		for _, limit := range finalResult.Limits.LikelyBreached {
+			fmt.Fprintf(stderr, "Error: command crashed, most likely by exceeding its %s limit\n", limit)
 		}
 	}
 
-	// Output result (unless quiet mode)
+	stopMirror()
+
+	// Output result (unless quiet mode); on assertion failure the screen is
+	// still output for debugging
 	if !cfg.quiet {
 		outputResult(finalResult, results, cfg, timing)
 	}
 
-	if timedOut {
+	if interrupted.Load() {
+		return ExitGeneralError
+	}
+
+	if !assertionsPassed {
+		return ExitAssertionFailed
+	}
+
+	if !exitPassed {
+		return ExitCommandError
+	}
+
+	if timedOut.Load() || !expectPassed || !stablePassed {
 		return ExitTimeout
 	}
 
 	return ExitSuccess
 }
 
+// keySequence converts a space-separated key spec (as for -shutdown-keys or
+// an -expect rule) to the bytes to send.
+func keySequence(spec string) string {
+	var sb strings.Builder
+	for _, part := range strings.Fields(spec) {
+		sb.WriteString(string(parseKey(part)))
+	}
+	return sb.String()
+}
+
+// evaluateChecks runs -check and -check-at against a capture.
+func evaluateChecks(cfg config, result CaptureResult) map[string]bool {
+	if len(cfg.checks) == 0 && len(cfg.checksAt) == 0 {
+		return nil
+	}
+	checks := make(map[string]bool)
+	for _, checkText := range cfg.checks {
+		checks[checkText] = strings.Contains(result.plainScreen, checkText)
+	}
+	for _, check := range cfg.checksAt {
+		checks[check.spec] = check.matches(result.snap)
+	}
+	return checks
+}
+
+// reportAssertion prints a failed assertion to stderr, the run's writer that
+// holds messages back while -mirror owns the terminal, and records it as a
+// test case.
+func reportAssertion(suite *testSuite, r AssertionResult, stderr io.Writer) {
+	name := fmt.Sprintf("%s %q", r.Kind, r.Text)
+	message := r.Message
+	if r.Step != nil {
+		name = fmt.Sprintf("step %d: %s", *r.Step, name)
+		message = fmt.Sprintf("step %d: %s", *r.Step, message)
+	}
+	if !r.Passed {
+		fmt.Fprintf(stderr, "Assertion failed: %s\n", message)
+	}
+	suite.add(testCase{
+		name:    name,
+		class:   "assert",
+		passed:  r.Passed,
+		message: message,
+	})
+}
+
+// initialWait describes the waits performed before the first capture.
+func initialWait(cfg config) string {
+	waits := []string{"delay " + cfg.delay.String()}
+	if cfg.waitForText != "" {
+		waits = append(waits, fmt.Sprintf("wait-for %q", cfg.waitForText))
+	}
+	if cfg.waitStable {
+		waits = append(waits, "stable")
+	}
+	return strings.Join(waits, ", ")
+}
+
+// checkCase reports a -check or -check-at result as a test case.
+func checkCase(text string, found bool) testCase {
+	tc := testCase{name: fmt.Sprintf("check %q", text), class: "check", passed: found}
+	if !found {
+		tc.message = fmt.Sprintf("text %q not found on screen", text)
+	}
+	return tc
+}
+
+// errorMessage returns err's message, or "" for a nil error.
+func errorMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
 func readKeysFromStdin() (string, error) {
 	var keys []string
 	scanner := bufio.NewScanner(os.Stdin)
@@ -369,23 +1143,60 @@ func readKeysFromStdin() (string, error) {
 }
 
 func captureScreen(term *terminal.Terminal, command string, args []string, cfg config, timing *TimingInfo) CaptureResult {
-	screen, cursorCol, cursorRow, cursorVisible := term.ScreenshotWithCursor()
+	full := term.Snapshot()
+	masked := cfg.mask.Apply(full)
+	if cfg.maskOutput {
+		full = masked
+	}
+	snap := full
+	if cfg.crop != nil {
+		snap = full.Crop(*cfg.crop)
+		masked = masked.Crop(*cfg.crop)
+	}
+
+	sinceChange := time.Since(term.LastChange())
+
+	plain := snap.String()
+	compare := masked.String()
+	screen := plain
+	overlay := overlayOptions{
+		cursor:    cfg.showCursor || cfg.outputFormat == "compact",
+		highlight: cfg.highlight,
+	}
+	if overlay.cursor || overlay.highlight {
+		screen = renderScreen(snap, overlay)
+	}
 
 	if cfg.trim {
+		plain = trimTrailingBlankLines(plain)
+		compare = trimTrailingBlankLines(compare)
 		screen = trimTrailingBlankLines(screen)
 	}
 
-	return CaptureResult{
+	result := CaptureResult{
 		Screen:        screen,
-		Cols:          cfg.cols,
-		Rows:          cfg.rows,
-		CursorCol:     cursorCol,
-		CursorRow:     cursorRow,
-		CursorVisible: cursorVisible,
+		Cols:          snap.Cols,
+		Rows:          snap.Rows,
+		CursorCol:     snap.CursorCol,
+		CursorRow:     snap.CursorRow,
+		CursorVisible: snap.CursorVisible,
 		Timestamp:     time.Now(),
+		Stable:        sinceChange >= cfg.stableTime,
+		SinceChangeMs: sinceChange.Milliseconds(),
 		Command:       command + " " + strings.Join(args, " "),
 		Timing:        timing,
+		Crop:          cfg.crop,
+		plainScreen:   plain,
+		compareScreen: compare,
+		snap:          full,
+		view:          snap,
+	}
+
+	if cfg.elements {
+		result.Elements = elements.Detect(snap)
 	}
+
+	return result
 }
 
 func trimTrailingBlankLines(s string) string {
@@ -413,25 +1224,59 @@ func outputResult(result CaptureResult, multiResults []CaptureResult, cfg config
 	switch cfg.outputFormat {
 	case "json":
 		if cfg.captureEach && len(multiResults) > 0 {
-			output = formatMultiJSON(multiResults, result.Command, timing)
+			output = formatMultiJSON(multiResults, result, timing)
 		} else {
 			output = formatJSON(result)
 		}
-	case "text":
+	case "svg":
+		output = render.SVG(result.view)
+	case "png":
+		var buf bytes.Buffer
+		if err := render.PNG(&buf, result.view); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: rendering PNG: %v\n", err)
+			return
+		}
+		output = buf.String()
+	case "gif":
+		// Animate every capture of -capture-each; otherwise a single frame
+		snaps := []terminal.Snapshot{result.view}
+		if cfg.captureEach && len(multiResults) > 0 {
+			snaps = snaps[:0]
+			for _, r := range multiResults {
+				snaps = append(snaps, r.view)
+			}
+		}
+		var buf bytes.Buffer
+		if err := render.GIF(&buf, snaps, cfg.frameDuration); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: rendering GIF: %v\n", err)
+			return
+		}
+		output = buf.String()
+	case "text", "compact":
 		if cfg.captureEach && len(multiResults) > 0 {
 			// For text mode with capture-each, show all captures separated by markers
 			var sb strings.Builder
 			for i, r := range multiResults {
 				if i > 0 {
-					sb.WriteString("\n--- Capture ")
+					if cfg.outputFormat == "text" {
+						sb.WriteString("\n")
+					}
+					sb.WriteString("--- Capture ")
 					sb.WriteString(fmt.Sprintf("%d", i))
 					sb.WriteString(" ---\n")
 				}
-				sb.WriteString(r.Screen)
+				if cfg.diff && i > 0 {
+					sb.WriteString(formatDiffText(r.Diff))
+					continue
+				}
+				sb.WriteString(formatScreen(r, cfg))
 			}
 			output = sb.String()
 		} else {
-			output = result.Screen
+			output = formatScreen(result, cfg)
+		}
+		if cfg.frames {
+			output += formatFramesText(result.Frames, cfg)
 		}
 	default:
 		output = result.Screen
@@ -448,7 +1293,15 @@ func outputResult(result CaptureResult, multiResults []CaptureResult, cfg config
 	}
 }
 
-func sendKeys(term *terminal.Terminal, keys string, inputDelay time.Duration) error {
+// formatScreen renders a single capture's screen for the text-based formats.
+func formatScreen(result CaptureResult, cfg config) string {
+	if cfg.outputFormat == "compact" {
+		return formatCompact(result, cfg.lineNumbers)
+	}
+	return result.Screen
+}
+
+func sendKeys(term *terminal.Terminal, keys string, inputDelay time.Duration, steps *stepLog) error {
 	// Parse key specification
 	// Supports: "down down enter" or literal strings
 	parts := strings.Split(keys, " ")
@@ -458,11 +1311,12 @@ func sendKeys(term *terminal.Terminal, keys string, inputDelay time.Duration) er
 			continue
 		}
 		key := parseKey(part)
+		steps.mark(part)
 		if err := term.SendKeys(string(key)); err != nil {
 			return err
 		}
 		// Delay between keys
-		time.Sleep(inputDelay)
+		term.Delay(inputDelay)
 	}
 
 	return nil
@@ -586,11 +1440,19 @@ func formatJSON(result CaptureResult) string {
 	return buf.String()
 }
 
-func formatMultiJSON(results []CaptureResult, command string, timing *TimingInfo) string {
+// formatMultiJSON formats the -capture-each captures; run-level fields come
+// from the final capture.
+func formatMultiJSON(results []CaptureResult, final CaptureResult, timing *TimingInfo) string {
 	multi := MultiCaptureResult{
-		Captures: results,
-		Command:  command,
-		Timing:   timing,
+		Captures:   results,
+		Command:    final.Command,
+		Timing:     timing,
+		Assertions: final.Assertions,
+		Env:        final.Env,
+		Shutdown:   final.Shutdown,
+		Limits:     final.Limits,
+		Expect:     final.Expect,
+		Frames:     final.Frames,
 	}
 	var buf strings.Builder
 	enc := json.NewEncoder(&buf)
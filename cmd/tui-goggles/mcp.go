@@ -0,0 +1,346 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// mcpProtocolVersions are the Model Context Protocol revisions we speak,
+// newest first.
+var mcpProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// JSON-RPC error codes.
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+)
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// mcpTool is a tool as listed by tools/list.
+type mcpTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+
+	handler func(m *sessionManager, args json.RawMessage) (any, error)
+}
+
+// mcpContent is an item of a tool result.
+type mcpContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type mcpToolResult struct {
+	Content []mcpContent `json:"content"`
+	IsError bool         `json:"isError,omitempty"`
+}
+
+// runMCP serves terminal sessions as Model Context Protocol tools over
+// stdio until stdin is closed, then stops every session.
+func runMCP(args []string) int {
+	fs := flag.NewFlagSet("mcp", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: tui-goggles mcp")
+		fmt.Fprintln(os.Stderr, "Serve terminal sessions as MCP tools over stdin/stdout.")
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return ExitGeneralError
+	}
+
+	sessions := newSessionManager()
+	defer sessions.stopAll()
+	if err := serveMCP(os.Stdin, os.Stdout, sessions); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return ExitGeneralError
+	}
+	return ExitSuccess
+}
+
+// serveMCP reads newline-delimited JSON-RPC messages from r and writes the
+// responses to w. Requests are handled concurrently; operations on one
+// session are serialized by the session.
+func serveMCP(r io.Reader, w io.Writer, sessions *sessionManager) error {
+	var writeMu sync.Mutex
+	enc := json.NewEncoder(w)
+	send := func(resp rpcResponse) {
+		resp.JSONRPC = "2.0"
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		if err := enc.Encode(resp); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: writing response: %v\n", err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var req rpcRequest
+			if jsonErr := json.Unmarshal(line, &req); jsonErr != nil {
+				send(rpcResponse{ID: json.RawMessage("null"), Error: &rpcError{Code: rpcParseError, Message: jsonErr.Error()}})
+			} else {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					result, rpcErr := handleMCP(req, sessions)
+					// Notifications get no response
+					if len(req.ID) == 0 {
+						return
+					}
+					send(rpcResponse{ID: req.ID, Result: result, Error: rpcErr})
+				}()
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// handleMCP handles one request or notification.
+func handleMCP(req rpcRequest, sessions *sessionManager) (any, *rpcError) {
+	switch req.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		_ = json.Unmarshal(req.Params, &params)
+		version := mcpProtocolVersions[0]
+		for _, v := range mcpProtocolVersions {
+			if v == params.ProtocolVersion {
+				version = v
+			}
+		}
+		return map[string]any{
+			"protocolVersion": version,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]any{"name": "tui-goggles", "version": "dev"},
+			"instructions": "Run TUI applications in a virtual terminal and read their screens. " +
+				"Start a session, send keys, capture the screen, and stop the session when done.",
+		}, nil
+	case "ping":
+		return map[string]any{}, nil
+	case "tools/list":
+		return map[string]any{"tools": mcpTools}, nil
+	case "tools/call":
+		var params struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+		}
+		for _, tool := range mcpTools {
+			if tool.Name == params.Name {
+				return callTool(tool, sessions, params.Arguments), nil
+			}
+		}
+		// Reported as a tool error, like bad arguments, so the model sees it
+		return mcpToolResult{
+			Content: []mcpContent{{Type: "text", Text: fmt.Sprintf("Error: unknown tool %q", params.Name)}},
+			IsError: true,
+		}, nil
+	case "":
+		return nil, &rpcError{Code: rpcInvalidRequest, Message: "missing method"}
+	}
+	return nil, &rpcError{Code: rpcMethodNotFound, Message: fmt.Sprintf("unknown method %q", req.Method)}
+}
+
+// callTool runs a tool. Failures are reported in the result, as the
+// protocol asks, together with whatever the tool returned (typically the
+// screen at the time).
+func callTool(tool mcpTool, sessions *sessionManager, args json.RawMessage) mcpToolResult {
+	if len(args) == 0 {
+		args = json.RawMessage("{}")
+	}
+	value, err := tool.handler(sessions, args)
+
+	var result mcpToolResult
+	if err != nil {
+		result.IsError = true
+		result.Content = append(result.Content, mcpContent{Type: "text", Text: "Error: " + err.Error()})
+	}
+	if value != nil {
+		data, _ := json.MarshalIndent(value, "", "  ")
+		result.Content = append(result.Content, mcpContent{Type: "text", Text: string(data)})
+	}
+	return result
+}
+
+// decodeArgs unmarshals tool arguments, rejecting unknown fields so typos
+// do not go unnoticed.
+func decodeArgs(args json.RawMessage, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(args))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("invalid arguments: %v", err)
+	}
+	return nil
+}
+
+// toolHandler adapts a session operation to a tool handler that decodes
+// its arguments.
+func toolHandler[A, R any](op func(*sessionManager, A) (*R, error)) func(*sessionManager, json.RawMessage) (any, error) {
+	return func(sessions *sessionManager, raw json.RawMessage) (any, error) {
+		var args A
+		if err := decodeArgs(raw, &args); err != nil {
+			return nil, err
+		}
+		result, err := op(sessions, args)
+		if result == nil {
+			return nil, err
+		}
+		return result, err
+	}
+}
+
+// JSON schema fragments shared by the tool definitions.
+var (
+	schemaSessionID = map[string]any{"type": "string", "description": "Session ID returned by start_session"}
+	schemaTimeout   = map[string]any{"type": "integer", "description": "How long to wait, in milliseconds (default 5000)"}
+	schemaCapture   = map[string]any{
+		"show_cursor": map[string]any{"type": "boolean", "description": "Mark the cursor position with ▌"},
+		"highlight":   map[string]any{"type": "boolean", "description": "Bracket reverse-video (selected) runs with « and »"},
+		"elements":    map[string]any{"type": "boolean", "description": "Detect panels, lists, status/tab bars and input fields"},
+		"trim":        map[string]any{"type": "boolean", "description": "Trim trailing blank lines from the screen"},
+	}
+)
+
+// objectSchema builds a tool input schema from properties (merged with the
+// capture options if withCapture) and the required property names.
+func objectSchema(props map[string]any, withCapture bool, required ...string) map[string]any {
+	all := make(map[string]any, len(props))
+	for name, p := range props {
+		all[name] = p
+	}
+	if withCapture {
+		for name, p := range schemaCapture {
+			all[name] = p
+		}
+	}
+	schema := map[string]any{"type": "object", "properties": all, "additionalProperties": false}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// mcpTools are the tools served by the mcp command.
+var mcpTools = []mcpTool{
+	{
+		Name: "start_session",
+		Description: "Start a command in a new virtual terminal session and return its first screen " +
+			"(after an initial delay and waiting for the screen to settle, or for wait_for text).",
+		InputSchema: objectSchema(map[string]any{
+			"command":    map[string]any{"type": "string", "description": "Program to run"},
+			"args":       map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "description": "Program arguments"},
+			"cols":       map[string]any{"type": "integer", "description": "Terminal width (default 80)"},
+			"rows":       map[string]any{"type": "integer", "description": "Terminal height (default 24)"},
+			"env":        map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "description": "Extra environment variables as KEY=VALUE"},
+			"cwd":        map[string]any{"type": "string", "description": "Working directory"},
+			"clean_env":  map[string]any{"type": "boolean", "description": "Start in a minimal fixed environment with a temporary HOME"},
+			"delay_ms":   map[string]any{"type": "integer", "description": "Initial delay before waiting, in milliseconds (default 500)"},
+			"wait_for":   map[string]any{"type": "string", "description": "Wait for this text instead of a stable screen"},
+			"timeout_ms": schemaTimeout,
+		}, true, "command"),
+		handler: toolHandler((*sessionManager).startSession),
+	},
+	{
+		Name: "send_keys",
+		Description: "Send keys to a session and return the screen once it settles. keys are space-separated " +
+			"key names as for -keys (e.g. \"down down enter\", \"ctrl-c\", \"hello\"); text is sent literally.",
+		InputSchema: objectSchema(map[string]any{
+			"session_id":     schemaSessionID,
+			"keys":           map[string]any{"type": "string", "description": "Space-separated key names"},
+			"text":           map[string]any{"type": "string", "description": "Literal text to type (sent after keys)"},
+			"input_delay_ms": map[string]any{"type": "integer", "description": "Delay between keys, in milliseconds (default 50)"},
+			"no_wait":        map[string]any{"type": "boolean", "description": "Return right after sending instead of waiting for the screen to settle"},
+			"timeout_ms":     schemaTimeout,
+		}, true, "session_id"),
+		handler: toolHandler((*sessionManager).sendSessionKeys),
+	},
+	{
+		Name:        "capture",
+		Description: "Return the current screen of a session without waiting.",
+		InputSchema: objectSchema(map[string]any{
+			"session_id": schemaSessionID,
+		}, true, "session_id"),
+		handler: toolHandler((*sessionManager).captureSession),
+	},
+	{
+		Name: "wait_for",
+		Description: "Wait until text appears on a session's screen, the screen is stable, or the command exits, " +
+			"then return the screen. Fails on timeout (the screen is still returned).",
+		InputSchema: objectSchema(map[string]any{
+			"session_id": schemaSessionID,
+			"text":       map[string]any{"type": "string", "description": "Text to wait for"},
+			"stable":     map[string]any{"type": "boolean", "description": "Wait for the screen to stop changing"},
+			"exit":       map[string]any{"type": "boolean", "description": "Wait for the command to exit"},
+			"timeout_ms": schemaTimeout,
+		}, true, "session_id"),
+		handler: toolHandler((*sessionManager).waitSession),
+	},
+	{
+		Name:        "resize",
+		Description: "Resize a session's terminal and return the screen once the app has redrawn.",
+		InputSchema: objectSchema(map[string]any{
+			"session_id": schemaSessionID,
+			"cols":       map[string]any{"type": "integer", "description": "New width"},
+			"rows":       map[string]any{"type": "integer", "description": "New height"},
+			"timeout_ms": schemaTimeout,
+		}, true, "session_id", "cols", "rows"),
+		handler: toolHandler((*sessionManager).resizeSession),
+	},
+	{
+		Name: "stop_session",
+		Description: "Stop a session: send shutdown_keys if given, then SIGTERM and SIGKILL to its process group. " +
+			"Returns the last screen and how the command ended.",
+		InputSchema: objectSchema(map[string]any{
+			"session_id":    schemaSessionID,
+			"shutdown_keys": map[string]any{"type": "string", "description": "Keys that ask the app to quit (e.g. \"q\")"},
+			"term_wait_ms":  map[string]any{"type": "integer", "description": "How long to wait after SIGTERM, in milliseconds (default 1000)"},
+		}, false, "session_id"),
+		handler: toolHandler((*sessionManager).stopSession),
+	},
+	{
+		Name:        "list_sessions",
+		Description: "List the running sessions.",
+		InputSchema: objectSchema(map[string]any{}, false),
+		handler:     toolHandler((*sessionManager).listSessions),
+	},
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// mcpReply is a JSON-RPC response as a client reads it.
+type mcpReply struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result"`
+	Error   *rpcError       `json:"error"`
+}
+
+// runMCPLines feeds newline-delimited messages through serveMCP and returns
+// the responses.
+func runMCPLines(t *testing.T, lines ...string) []mcpReply {
+	t.Helper()
+	sessions := newSessionManager()
+	defer sessions.stopAll()
+	var out bytes.Buffer
+	if err := serveMCP(strings.NewReader(strings.Join(lines, "\n")+"\n"), &out, sessions); err != nil {
+		t.Fatal(err)
+	}
+	var replies []mcpReply
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var r mcpReply
+		if err := dec.Decode(&r); err != nil {
+			t.Fatalf("response is not JSON: %v\n%s", err, out.String())
+		}
+		if r.JSONRPC != "2.0" {
+			t.Errorf("jsonrpc = %q, want 2.0", r.JSONRPC)
+		}
+		replies = append(replies, r)
+	}
+	return replies
+}
+
+func TestServeMCP(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		// wantResult is a substring of the result; wantCode the error code.
+		// Neither means no reply at all.
+		wantResult string
+		wantCode   int
+	}{
+		{
+			name:       "initialize with a known version",
+			line:       `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05"}}`,
+			wantResult: `"protocolVersion":"2024-11-05"`,
+		},
+		{
+			name:       "initialize with an unknown version",
+			line:       `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"1999-01-01"}}`,
+			wantResult: `"protocolVersion":"` + mcpProtocolVersions[0] + `"`,
+		},
+		{
+			name:       "tools/list",
+			line:       `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+			wantResult: `"name":"start_session"`,
+		},
+		{
+			name:       "ping",
+			line:       `{"jsonrpc":"2.0","id":3,"method":"ping"}`,
+			wantResult: `{}`,
+		},
+		{
+			name:       "unknown tool",
+			line:       `{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"explode"}}`,
+			wantResult: `"isError":true`,
+		},
+		{
+			name:       "bad tool arguments",
+			line:       `{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"capture","arguments":{"sesion_id":"x"}}}`,
+			wantResult: `"isError":true`,
+		},
+		{
+			name:       "unknown session",
+			line:       `{"jsonrpc":"2.0","id":6,"method":"tools/call","params":{"name":"capture","arguments":{"session_id":"x"}}}`,
+			wantResult: `"isError":true`,
+		},
+		{
+			name: "notification",
+			line: `{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		},
+		{
+			name: "unknown notification",
+			line: `{"jsonrpc":"2.0","method":"notifications/whatever"}`,
+		},
+		{
+			name:     "unknown method",
+			line:     `{"jsonrpc":"2.0","id":7,"method":"resources/list"}`,
+			wantCode: rpcMethodNotFound,
+		},
+		{
+			name:     "missing method",
+			line:     `{"jsonrpc":"2.0","id":8}`,
+			wantCode: rpcInvalidRequest,
+		},
+		{
+			name:     "malformed JSON",
+			line:     `{"jsonrpc":"2.0","id":9,`,
+			wantCode: rpcParseError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replies := runMCPLines(t, tt.line)
+			if tt.wantResult == "" && tt.wantCode == 0 {
+				if len(replies) != 0 {
+					t.Fatalf("got %d replies to a notification, want none", len(replies))
+				}
+				return
+			}
+			if len(replies) != 1 {
+				t.Fatalf("got %d replies, want 1", len(replies))
+			}
+			r := replies[0]
+			if tt.wantCode != 0 {
+				if r.Error == nil || r.Error.Code != tt.wantCode {
+					t.Errorf("error = %+v, want code %d", r.Error, tt.wantCode)
+				}
+				return
+			}
+			if r.Error != nil {
+				t.Fatalf("error = %+v, want a result", r.Error)
+			}
+			var compact bytes.Buffer
+			if err := json.Compact(&compact, r.Result); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(compact.String(), tt.wantResult) {
+				t.Errorf("result = %s, want it to contain %s", compact.String(), tt.wantResult)
+			}
+		})
+	}
+}
+
+func TestServeMCPMalformedID(t *testing.T) {
+	replies := runMCPLines(t, `not json`)
+	if len(replies) != 1 || string(replies[0].ID) != "null" {
+		t.Fatalf("replies = %+v, want one with id null", replies)
+	}
+}
+
+func TestServeMCPSession(t *testing.T) {
+	replies := runMCPLines(t,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"start_session","arguments":{"command":"sh","args":["-c","echo hello; sleep 5"],"wait_for":"hello"}}}`,
+	)
+	if len(replies) != 1 || replies[0].Error != nil {
+		t.Fatalf("replies = %+v, want one result", replies)
+	}
+	var result mcpToolResult
+	if err := json.Unmarshal(replies[0].Result, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.IsError || len(result.Content) != 1 || !strings.Contains(result.Content[0].Text, "hello") {
+		t.Errorf("start_session = %+v, want the screen showing hello", result)
+	}
+}
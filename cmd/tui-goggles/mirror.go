@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/creack/pty"
+	"github.com/your-username/tui-goggles/internal/render"
+	"github.com/your-username/tui-goggles/internal/terminal"
+)
+
+// mirrorInterval coalesces bursts of screen updates into one redraw.
+const mirrorInterval = 30 * time.Millisecond
+
+// mirror draws the virtual screen into the real terminal (-mirror) as it
+// changes, so a scripted run can be watched.
+type mirror struct {
+	tty  *os.File
+	term *terminal.Terminal
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+	// rows is how many rows the last frame drew.
+	rows int
+
+	// stderr takes our messages while mirroring, holding them back as they
+	// would land wherever the application's cursor is and be drawn over.
+	stderr *heldWriter
+}
+
+// startMirror clears the real terminal and starts redrawing the virtual
+// screen on it.
+func startMirror(term *terminal.Terminal) (*mirror, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		return nil, fmt.Errorf("-mirror needs a terminal: %w", err)
+	}
+	m := &mirror{
+		tty:    tty,
+		term:   term,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+		stderr: &heldWriter{out: os.Stderr},
+	}
+
+	_, _ = io.WriteString(tty, "\x1b[H\x1b[2J")
+	go m.loop()
+	return m, nil
+}
+
+func (m *mirror) loop() {
+	defer close(m.done)
+	for {
+		changed := m.term.Changed()
+		m.draw()
+		select {
+		case <-m.stop:
+			return
+		case <-m.term.Done():
+			m.draw()
+			<-m.stop
+			return
+		case <-changed:
+			time.Sleep(mirrorInterval)
+		}
+	}
+}
+
+// draw redraws the screen, clipped to the real terminal's size.
+func (m *mirror) draw() {
+	snap := m.term.Peek()
+	if rows, cols, err := pty.Getsize(m.tty); err == nil && cols > 0 && rows > 0 && (cols < snap.Cols || rows < snap.Rows) {
+		snap = snap.Crop(terminal.Rect{Width: cols, Height: rows})
+	}
+	m.rows = snap.Rows
+	_, _ = io.WriteString(m.tty, render.ANSI(snap))
+}
+
+// close stops redrawing, moves the cursor below the last frame (which stays
+// on screen) and writes out the messages held back in stderr. It is safe to
+// call more than once.
+func (m *mirror) close() {
+	m.closeOnce.Do(func() {
+		close(m.stop)
+		<-m.done
+		fmt.Fprintf(m.tty, "\x1b[0m\x1b[?25h\x1b[%d;1H\r\n", m.rows)
+		m.tty.Close()
+		m.stderr.release()
+	})
+}
+
+// heldWriter holds back what is written to it until release, then writes
+// that out and passes later writes straight through.
+type heldWriter struct {
+	mu       sync.Mutex
+	out      io.Writer
+	held     bytes.Buffer
+	released bool
+}
+
+func (w *heldWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.released {
+		return w.out.Write(p)
+	}
+	return w.held.Write(p)
+}
+
+func (w *heldWriter) release() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.released = true
+	_, _ = w.out.Write(w.held.Bytes())
+	w.held.Reset()
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/your-username/tui-goggles/internal/terminal"
+)
+
+// cursorMarker is inserted at the cursor position.
+const cursorMarker = "▌"
+
+// Markers used to bracket highlighted (reverse-video) cell runs.
+const (
+	highlightStart = "«"
+	highlightEnd   = "»"
+)
+
+// overlayOptions selects which markers are drawn into a rendered screen.
+type overlayOptions struct {
+	cursor    bool
+	highlight bool
+}
+
+// renderScreen renders a snapshot as text, inserting the cursor marker and
+// highlight brackets requested by opts. Markers are inserted rather than
+// drawn over cells, so columns to the right of a marker shift accordingly.
+func renderScreen(snap terminal.Snapshot, opts overlayOptions) string {
+	var sb strings.Builder
+
+	for y, row := range snap.Cells {
+		inHighlight := false
+		for x, cell := range row {
+			highlighted := opts.highlight && cell.Has(terminal.AttrReverse)
+			if highlighted && !inHighlight {
+				sb.WriteString(highlightStart)
+			} else if !highlighted && inHighlight {
+				sb.WriteString(highlightEnd)
+			}
+			inHighlight = highlighted
+
+			if opts.cursor && snap.CursorVisible && y == snap.CursorRow && x == snap.CursorCol {
+				sb.WriteString(cursorMarker)
+			}
+			sb.WriteRune(cell.Char)
+		}
+		if inHighlight {
+			sb.WriteString(highlightEnd)
+		}
+		sb.WriteByte('\n')
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,98 @@
+package main
+
+import "testing"
+
+func TestRenderScreen(t *testing.T) {
+	tests := []struct {
+		name    string
+		lines   []string
+		reverse []string
+		cursor  []int // row, col; nil for a hidden cursor
+		opts    overlayOptions
+		want    string
+	}{
+		{
+			name:  "no markers",
+			lines: []string{"ab", "cd"},
+			want:  "ab\ncd\n",
+		},
+		{
+			name:   "cursor marker before its cell",
+			lines:  []string{"ab", "cd"},
+			cursor: []int{1, 1},
+			opts:   overlayOptions{cursor: true},
+			want:   "ab\nc▌d\n",
+		},
+		{
+			name:   "cursor marker at the start of a row",
+			lines:  []string{"ab", "cd"},
+			cursor: []int{0, 0},
+			opts:   overlayOptions{cursor: true},
+			want:   "▌ab\ncd\n",
+		},
+		{
+			name:  "hidden cursor has no marker",
+			lines: []string{"ab", "cd"},
+			opts:  overlayOptions{cursor: true},
+			want:  "ab\ncd\n",
+		},
+		{
+			name:   "cursor not requested",
+			lines:  []string{"ab", "cd"},
+			cursor: []int{0, 1},
+			want:   "ab\ncd\n",
+		},
+		{
+			name:    "highlight brackets a reverse run",
+			lines:   []string{"  Save  Quit"},
+			reverse: []string{"  rrrr"},
+			opts:    overlayOptions{highlight: true},
+			want:    "  «Save»  Quit\n",
+		},
+		{
+			name:    "highlight run at the right edge",
+			lines:   []string{"File  Edit"},
+			reverse: []string{"      rrrr"},
+			opts:    overlayOptions{highlight: true},
+			want:    "File  «Edit»\n",
+		},
+		{
+			name:    "separate runs on one row",
+			lines:   []string{"a b c"},
+			reverse: []string{"r   r"},
+			opts:    overlayOptions{highlight: true},
+			want:    "«a» b «c»\n",
+		},
+		{
+			name:    "highlight not requested",
+			lines:   []string{"  Save"},
+			reverse: []string{"  rrrr"},
+			want:    "  Save\n",
+		},
+		{
+			name:    "cursor inside a highlight",
+			lines:   []string{"Save"},
+			reverse: []string{"rrrr"},
+			cursor:  []int{0, 2},
+			opts:    overlayOptions{cursor: true, highlight: true},
+			want:    "«Sa▌ve»\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			width := 0
+			for _, line := range tt.lines {
+				if n := len([]rune(line)); n > width {
+					width = n
+				}
+			}
+			snap := testCapture(width, tt.lines, tt.reverse...).snap
+			if tt.cursor != nil {
+				snap.CursorRow, snap.CursorCol, snap.CursorVisible = tt.cursor[0], tt.cursor[1], true
+			}
+			if got := renderScreen(snap, tt.opts); got != tt.want {
+				t.Errorf("renderScreen() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+func makeRaw(f *os.File) (restore func(), err error) {
+	return nil, errors.New("attach is only supported on Linux")
+}
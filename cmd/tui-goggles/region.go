@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/your-username/tui-goggles/internal/terminal"
+)
+
+// regionMatch is a text expectation scoped to a rectangle of the screen,
+// parsed from "row,col,width[,height]:text".
+type regionMatch struct {
+	spec string
+	rect terminal.Rect
+	text string
+}
+
+// parseRegionMatch parses a region-scoped assertion or check. Width may be
+// omitted to mean "to the end of the row"; height defaults to 1.
+func parseRegionMatch(spec string) (regionMatch, error) {
+	coords, text, ok := strings.Cut(spec, ":")
+	if !ok || text == "" {
+		return regionMatch{}, fmt.Errorf("invalid region %q: expected row,col,width:text", spec)
+	}
+
+	rect, err := parseRect(coords, false)
+	if err != nil {
+		return regionMatch{}, fmt.Errorf("invalid region %q: %w", spec, err)
+	}
+
+	return regionMatch{spec: spec, rect: rect, text: text}, nil
+}
+
+// parseRect parses "row,col[,width[,height]]". When requireSize is set all
+// four values must be given, as for -crop.
+func parseRect(s string, requireSize bool) (terminal.Rect, error) {
+	parts := strings.Split(s, ",")
+	minParts := 2
+	if requireSize {
+		minParts = 4
+	}
+	if len(parts) < minParts || len(parts) > 4 {
+		if requireSize {
+			return terminal.Rect{}, fmt.Errorf("expected row,col,width,height")
+		}
+		return terminal.Rect{}, fmt.Errorf("expected row,col[,width[,height]]")
+	}
+
+	values := make([]int, len(parts))
+	for i, p := range parts {
+		v, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil || v < 0 {
+			return terminal.Rect{}, fmt.Errorf("%q is not a non-negative integer", p)
+		}
+		values[i] = v
+	}
+
+	rect := terminal.Rect{
+		Row:    values[0],
+		Col:    values[1],
+		Width:  maxTerminalCells,
+		Height: 1,
+	}
+	if len(values) > 2 {
+		rect.Width = values[2]
+	}
+	if len(values) > 3 {
+		rect.Height = values[3]
+	}
+	if rect.Width == 0 || rect.Height == 0 {
+		return terminal.Rect{}, fmt.Errorf("width and height must be positive")
+	}
+	return rect, nil
+}
+
+// checkOnScreen reports an error if a rectangle starts outside a screen of
+// the given size, where no text could ever be found in it.
+func checkOnScreen(r terminal.Rect, cols, rows int) error {
+	if r.Row >= rows || r.Col >= cols {
+		return fmt.Errorf("row %d, col %d is outside the %dx%d screen", r.Row, r.Col, cols, rows)
+	}
+	return nil
+}
+
+// maxTerminalCells stands in for "to the edge of the screen"; rectangles are
+// clipped to the screen before use.
+const maxTerminalCells = 1 << 16
+
+// matches reports whether the region of the snapshot contains the text.
+func (m regionMatch) matches(snap terminal.Snapshot) bool {
+	return strings.Contains(snap.RegionText(m.rect), m.text)
+}
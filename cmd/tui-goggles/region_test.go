@@ -0,0 +1,100 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/your-username/tui-goggles/internal/terminal"
+)
+
+func TestParseRegionMatch(t *testing.T) {
+	tests := []struct {
+		spec     string
+		wantRect terminal.Rect
+		wantText string
+		wantErr  bool
+	}{
+		{spec: "1,2,10:OK", wantRect: terminal.Rect{Row: 1, Col: 2, Width: 10, Height: 1}, wantText: "OK"},
+		{spec: "1,2,10,3:OK", wantRect: terminal.Rect{Row: 1, Col: 2, Width: 10, Height: 3}, wantText: "OK"},
+		{spec: "0,5:OK", wantRect: terminal.Rect{Row: 0, Col: 5, Width: maxTerminalCells, Height: 1}, wantText: "OK"},
+		{spec: " 1 , 2 ,3:a:b", wantRect: terminal.Rect{Row: 1, Col: 2, Width: 3, Height: 1}, wantText: "a:b"},
+
+		{spec: "1,2,10", wantErr: true},
+		{spec: "1,2,10:", wantErr: true},
+		{spec: "1:OK", wantErr: true},
+		{spec: "1,2,3,4,5:OK", wantErr: true},
+		{spec: "a,2:OK", wantErr: true},
+		{spec: "-1,2:OK", wantErr: true},
+		{spec: "1,2,0:OK", wantErr: true},
+		{spec: "1,2,5,0:OK", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			m, err := parseRegionMatch(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseRegionMatch(%q) = %+v, want error", tt.spec, m)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.rect != tt.wantRect || m.text != tt.wantText || m.spec != tt.spec {
+				t.Errorf("parseRegionMatch(%q) = %+v, want rect %+v, text %q", tt.spec, m, tt.wantRect, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestParseRectRequireSize(t *testing.T) {
+	if _, err := parseRect("1,2", true); err == nil {
+		t.Error("parseRect without a size succeeded, want error")
+	}
+	r, err := parseRect("1,2,3,4", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (terminal.Rect{Row: 1, Col: 2, Width: 3, Height: 4}); r != want {
+		t.Errorf("parseRect() = %+v, want %+v", r, want)
+	}
+}
+
+func TestCheckOnScreen(t *testing.T) {
+	tests := []struct {
+		rect    terminal.Rect
+		wantErr bool
+	}{
+		{terminal.Rect{Row: 0, Col: 0, Width: 10, Height: 1}, false},
+		{terminal.Rect{Row: 23, Col: 79, Width: maxTerminalCells, Height: 5}, false},
+		{terminal.Rect{Row: 24, Col: 0, Width: 10, Height: 1}, true},
+		{terminal.Rect{Row: 0, Col: 80, Width: 10, Height: 1}, true},
+	}
+	for _, tt := range tests {
+		if err := checkOnScreen(tt.rect, 80, 24); (err != nil) != tt.wantErr {
+			t.Errorf("checkOnScreen(%+v) = %v, want error %v", tt.rect, err, tt.wantErr)
+		}
+	}
+}
+
+func TestRegionMatches(t *testing.T) {
+	snap := testCapture(10, []string{"top  line", "Status: on"}).snap
+	tests := []struct {
+		spec string
+		want bool
+	}{
+		{"1,0,10:on", true},
+		{"1,0,6:on", false},
+		{"0,0,10,2:Status", true},
+		{"5,0,10:on", false},
+		{"0,8,10:e", true},
+	}
+	for _, tt := range tests {
+		m, err := parseRegionMatch(tt.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := m.matches(snap); got != tt.want {
+			t.Errorf("%s: matches() = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+}
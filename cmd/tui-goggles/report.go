@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Report formats accepted by -report.
+const (
+	reportJUnit = "junit"
+	reportTAP   = "tap"
+)
+
+// reportSpec is a parsed -report flag: a format and an optional output path
+// (stderr when empty, so stdout keeps the capture).
+type reportSpec struct {
+	format string
+	path   string
+}
+
+// parseReportSpec parses "format[=path]".
+func parseReportSpec(spec string) (reportSpec, error) {
+	format, path, _ := strings.Cut(spec, "=")
+	switch format {
+	case reportJUnit, reportTAP:
+		return reportSpec{format: format, path: path}, nil
+	default:
+		return reportSpec{}, fmt.Errorf("unknown report format %q (expected junit or tap)", format)
+	}
+}
+
+// testCase is one reported expectation: an assertion, a check, a wait step
+// or the command's exit status.
+type testCase struct {
+	name     string
+	class    string
+	passed   bool
+	message  string
+	duration time.Duration
+}
+
+// testSuite collects the test cases of a run for the -report writers.
+type testSuite struct {
+	name    string
+	started time.Time
+	cases   []testCase
+	// screen is attached to failing test cases.
+	screen string
+}
+
+func newTestSuite(name string) *testSuite {
+	return &testSuite{name: name, started: time.Now()}
+}
+
+// setScreen records the screen attached to failures, without the padding
+// that only matters for the grid layout.
+func (s *testSuite) setScreen(screen string) {
+	lines := strings.Split(trimTrailingBlankLines(screen), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	s.screen = strings.Join(lines, "\n")
+}
+
+func (s *testSuite) add(tc testCase) {
+	s.cases = append(s.cases, tc)
+}
+
+func (s *testSuite) failures() int {
+	n := 0
+	for _, tc := range s.cases {
+		if !tc.passed {
+			n++
+		}
+	}
+	return n
+}
+
+// writeReports writes the suite in every requested format. Reports without
+// a path, and errors, go to stderr, keeping stdout for the capture.
+func writeReports(specs []reportSpec, suite *testSuite, stderr io.Writer) {
+	for _, spec := range specs {
+		var output string
+		switch spec.format {
+		case reportJUnit:
+			output = formatJUnit(suite)
+		case reportTAP:
+			output = formatTAP(suite)
+		}
+
+		if spec.path == "" {
+			_, _ = io.WriteString(stderr, output)
+			continue
+		}
+		if err := os.WriteFile(spec.path, []byte(output), 0644); err != nil {
+			fmt.Fprintf(stderr, "Error: writing %s report to %q: %v\n", spec.format, spec.path, err)
+		}
+	}
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut *junitCDATA   `xml:"system-out,omitempty"`
+}
+
+type junitCDATA struct {
+	Text string `xml:",cdata"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func formatJUnit(suite *testSuite) string {
+	js := junitTestSuite{
+		Name:      suite.name,
+		Tests:     len(suite.cases),
+		Failures:  suite.failures(),
+		Time:      seconds(time.Since(suite.started)),
+		Timestamp: suite.started.Format(time.RFC3339),
+	}
+
+	for _, tc := range suite.cases {
+		jc := junitTestCase{
+			Name:      tc.name,
+			Classname: "tui-goggles." + tc.class,
+			Time:      seconds(tc.duration),
+		}
+		if !tc.passed {
+			jc.Failure = &junitFailure{Message: tc.message, Type: tc.class, Text: tc.message}
+			jc.SystemOut = &junitCDATA{Text: suite.screen}
+		}
+		js.Cases = append(js.Cases, jc)
+	}
+
+	out, err := xml.MarshalIndent(junitTestSuites{Suites: []junitTestSuite{js}}, "", "  ")
+	if err != nil {
+		// Only reachable with invalid struct tags.
+		return ""
+	}
+	return xml.Header + string(out) + "\n"
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+func formatTAP(suite *testSuite) string {
+	var sb strings.Builder
+	sb.WriteString("TAP version 13\n")
+	sb.WriteString(fmt.Sprintf("1..%d\n", len(suite.cases)))
+
+	for i, tc := range suite.cases {
+		status := "ok"
+		if !tc.passed {
+			status = "not ok"
+		}
+		sb.WriteString(fmt.Sprintf("%s %d - %s\n", status, i+1, tc.name))
+		if tc.passed {
+			continue
+		}
+
+		sb.WriteString("  ---\n")
+		sb.WriteString(fmt.Sprintf("  message: %q\n", tc.message))
+		sb.WriteString(fmt.Sprintf("  type: %s\n", tc.class))
+		if suite.screen != "" {
+			sb.WriteString("  screen: |\n")
+			for _, line := range strings.Split(suite.screen, "\n") {
+				sb.WriteString("    ")
+				sb.WriteString(line)
+				sb.WriteString("\n")
+			}
+		}
+		sb.WriteString("  ...\n")
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseReportSpec(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    reportSpec
+		wantErr bool
+	}{
+		{spec: "junit", want: reportSpec{format: reportJUnit}},
+		{spec: "tap=out.tap", want: reportSpec{format: reportTAP, path: "out.tap"}},
+		{spec: "xml=out.xml", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseReportSpec(tt.spec)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseReportSpec(%q) = %+v, %v; want %+v, error %v", tt.spec, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestWriteReports(t *testing.T) {
+	suite := newTestSuite("app")
+	suite.add(testCase{name: `contains "Ready"`, class: "assert", passed: true})
+
+	path := filepath.Join(t.TempDir(), "report.xml")
+	var stderr bytes.Buffer
+	writeReports([]reportSpec{{format: reportTAP}, {format: reportJUnit, path: path}}, suite, &stderr)
+
+	if !strings.HasPrefix(stderr.String(), "TAP version 13\n") {
+		t.Errorf("report without a path did not go to stderr: %q", stderr.String())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "<testsuites") {
+		t.Errorf("JUnit report = %q", data)
+	}
+}
@@ -0,0 +1,498 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/your-username/tui-goggles/internal/render"
+	"github.com/your-username/tui-goggles/internal/terminal"
+)
+
+const (
+	// sseKeepAlive is how often an idle event stream gets a comment, so
+	// proxies do not close it (it also keeps the session from being reaped).
+	sseKeepAlive = 15 * time.Second
+	// sseMinInterval coalesces bursts of screen updates into one event.
+	sseMinInterval = 50 * time.Millisecond
+)
+
+// runServe serves terminal sessions over an HTTP/JSON API until it is
+// signalled, then stops every session.
+func runServe(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	listen := fs.String("listen", "127.0.0.1:7777", "Address to listen on")
+	idleTimeout := fs.Duration("idle-timeout", 10*time.Minute, "Stop sessions unused for this long (0 to keep them until deleted)")
+	token := fs.String("token", os.Getenv(serveTokenEnv), "Bearer token clients must send (default $"+serveTokenEnv+", or a random one printed at startup)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: tui-goggles serve [-listen addr] [-idle-timeout d] [-token t]")
+		fmt.Fprintln(os.Stderr, "Serve terminal sessions over an HTTP/JSON API.")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return ExitGeneralError
+	}
+
+	hosts, err := allowedHosts(*listen)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: -listen: %v\n", err)
+		return ExitGeneralError
+	}
+	if *token == "" {
+		*token = newServeToken()
+	}
+
+	sessions := newSessionManager()
+	defer sessions.stopAll()
+	server := &http.Server{
+		Addr:              *listen,
+		Handler:           &apiServer{sessions: sessions, token: *token, hosts: hosts},
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *idleTimeout > 0 {
+		go reapIdle(ctx, sessions, *idleTimeout)
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Fprintf(os.Stderr, "tui-goggles: listening on http://%s\n", *listen)
+	fmt.Fprintf(os.Stderr, "tui-goggles: token %s (send \"Authorization: Bearer <token>\"; attach reads $%s)\n", *token, serveTokenEnv)
+	if !isLoopback(*listen) {
+		fmt.Fprintln(os.Stderr, "tui-goggles: warning: not listening on loopback; anyone who can reach this address and has the token can run commands")
+	}
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return ExitGeneralError
+	}
+	return ExitSuccess
+}
+
+// reapIdle periodically stops sessions that have been idle for too long.
+func reapIdle(ctx context.Context, sessions *sessionManager, idle time.Duration) {
+	interval := idle / 4
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, id := range sessions.reap(idle) {
+				fmt.Fprintf(os.Stderr, "tui-goggles: stopped session %s after %v idle\n", id, idle)
+			}
+		}
+	}
+}
+
+// apiServer routes the session API:
+//
+//	POST   /sessions             start a session
+//	GET    /sessions             list sessions
+//	GET    /sessions/{id}        current screen (?format=json|text|cells|ansi)
+//	DELETE /sessions/{id}        stop a session
+//	POST   /sessions/{id}/keys   send keys
+//	POST   /sessions/{id}/wait   wait for text, a stable screen or exit
+//	POST   /sessions/{id}/resize resize the terminal
+//	GET    /sessions/{id}/events Server-Sent Events of screen changes
+//
+// Every request needs the bearer token and a Host naming the server (see
+// authorize); bodies must be application/json.
+type apiServer struct {
+	sessions *sessionManager
+	token    string
+	hosts    map[string]bool
+}
+
+func (a *apiServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !a.authorize(w, r) {
+		return
+	}
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if parts[0] != "sessions" || len(parts) > 3 {
+		writeAPIError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodPost:
+		var args startSessionArgs
+		if !decodeBody(w, r, &args) {
+			return
+		}
+		result, err := a.sessions.startSession(args)
+		writeAPIResult(w, http.StatusCreated, result, err)
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		list, err := a.sessions.listSessions(struct{}{})
+		writeAPIResult(w, http.StatusOK, list, err)
+	case len(parts) == 1:
+		writeAPIError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	case len(parts) == 2:
+		a.serveSession(w, r, parts[1])
+	default:
+		a.serveSessionAction(w, r, parts[1], parts[2])
+	}
+}
+
+func (a *apiServer) serveSession(w http.ResponseWriter, r *http.Request, id string) {
+	switch r.Method {
+	case http.MethodGet:
+		opts, err := queryCaptureOptions(r)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, err)
+			return
+		}
+		s, err := a.sessions.get(id)
+		if err != nil {
+			writeAPIError(w, http.StatusNotFound, err)
+			return
+		}
+		switch format := r.URL.Query().Get("format"); format {
+		case "", "json":
+			writeJSON(w, http.StatusOK, s.result(s.capture(opts)))
+		case "text":
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			_, _ = io.WriteString(w, s.capture(opts).Screen+"\n")
+		case "cells":
+			writeJSON(w, http.StatusOK, newCellsResult(s))
+		case "ansi":
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			_, _ = io.WriteString(w, render.ANSI(s.term.Peek()))
+		default:
+			writeAPIError(w, http.StatusBadRequest, fmt.Errorf("unknown format %q (json, text, cells or ansi)", format))
+		}
+	case http.MethodDelete:
+		var args stopSessionArgs
+		if r.ContentLength != 0 && !decodeBody(w, r, &args) {
+			return
+		}
+		args.SessionID = id
+		result, err := a.sessions.stopSession(args)
+		writeAPIResult(w, http.StatusOK, result, err)
+	default:
+		writeAPIError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	}
+}
+
+func (a *apiServer) serveSessionAction(w http.ResponseWriter, r *http.Request, id, action string) {
+	if action == "events" {
+		if r.Method != http.MethodGet {
+			writeAPIError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+			return
+		}
+		a.serveEvents(w, r, id)
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeAPIError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+
+	switch action {
+	case "keys":
+		var args sendKeysArgs
+		if !decodeBody(w, r, &args) {
+			return
+		}
+		args.SessionID = id
+		result, err := a.sessions.sendSessionKeys(args)
+		writeAPIResult(w, http.StatusOK, result, err)
+	case "wait":
+		var args waitForArgs
+		if !decodeBody(w, r, &args) {
+			return
+		}
+		args.SessionID = id
+		result, err := a.sessions.waitSession(args)
+		writeAPIResult(w, http.StatusOK, result, err)
+	case "resize":
+		var args resizeArgs
+		if !decodeBody(w, r, &args) {
+			return
+		}
+		args.SessionID = id
+		result, err := a.sessions.resizeSession(args)
+		writeAPIResult(w, http.StatusOK, result, err)
+	default:
+		writeAPIError(w, http.StatusNotFound, errors.New("not found"))
+	}
+}
+
+// serveEvents streams the session's screen as Server-Sent Events: a
+// "screen" event with the capture JSON whenever it changes (starting with
+// the current screen), and an "exit" event when the command's output ends.
+// With ?format=ansi the screen events carry escape sequences that redraw
+// the screen instead, clipped to ?cols and ?rows if given, as used by the
+// attach command.
+func (a *apiServer) serveEvents(w http.ResponseWriter, r *http.Request, id string) {
+	opts, err := queryCaptureOptions(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "ansi" {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("unknown format %q (json or ansi)", format))
+		return
+	}
+	// ANSI frames can be clipped to the viewer's terminal
+	var clip terminal.Rect
+	for name, dst := range map[string]*int{"cols": &clip.Width, "rows": &clip.Height} {
+		if v := r.URL.Query().Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				writeAPIError(w, http.StatusBadRequest, fmt.Errorf("invalid %s %q", name, v))
+				return
+			}
+			*dst = n
+		}
+	}
+	s, err := a.sessions.get(id)
+	if err != nil {
+		writeAPIError(w, http.StatusNotFound, err)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeAPIError(w, http.StatusInternalServerError, errors.New("streaming not supported"))
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(event string, v any) {
+		data, _ := json.Marshal(v)
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+		flusher.Flush()
+		a.sessions.touch(id)
+	}
+	last := ""
+	sendScreen := func() {
+		if format == "ansi" {
+			snap := s.term.Peek()
+			if clip.Width > 0 || clip.Height > 0 {
+				view := terminal.Rect{Width: snap.Cols, Height: snap.Rows}
+				if clip.Width > 0 && clip.Width < view.Width {
+					view.Width = clip.Width
+				}
+				if clip.Height > 0 && clip.Height < view.Height {
+					view.Height = clip.Height
+				}
+				snap = snap.Crop(view)
+			}
+			frame := render.ANSI(snap)
+			if frame != last {
+				last = frame
+				send("screen", ansiFrame{SessionID: id, Cols: snap.Cols, Rows: snap.Rows, ANSI: frame})
+			}
+			return
+		}
+		capture := s.capture(opts)
+		key := fmt.Sprintf("%s\x00%d,%d,%t", capture.Screen, capture.CursorRow, capture.CursorCol, capture.CursorVisible)
+		if key == last {
+			return
+		}
+		last = key
+		send("screen", s.result(capture))
+	}
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+	sendScreen()
+	for {
+		changed := s.term.Changed()
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.term.Done():
+			sendScreen()
+			send("exit", map[string]any{"session_id": id, "running": false})
+			return
+		case <-changed:
+			// Let a redraw finish rather than sending every partial frame
+			time.Sleep(sseMinInterval)
+			sendScreen()
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+			a.sessions.touch(id)
+		}
+	}
+}
+
+// ansiFrame is a screen event of an ?format=ansi event stream.
+type ansiFrame struct {
+	SessionID string `json:"session_id"`
+	Cols      int    `json:"cols"`
+	Rows      int    `json:"rows"`
+	ANSI      string `json:"ansi"`
+}
+
+// cellsResult is the cell grid of a screen, for ?format=cells.
+type cellsResult struct {
+	SessionID     string       `json:"session_id"`
+	Cols          int          `json:"cols"`
+	Rows          int          `json:"rows"`
+	CursorRow     int          `json:"cursor_row"`
+	CursorCol     int          `json:"cursor_col"`
+	CursorVisible bool         `json:"cursor_visible"`
+	Cells         [][]cellJSON `json:"cells"`
+}
+
+// cellJSON is one cell, with colors resolved to #rrggbb.
+type cellJSON struct {
+	Char  string   `json:"char"`
+	FG    string   `json:"fg"`
+	BG    string   `json:"bg"`
+	Attrs []string `json:"attrs,omitempty"`
+}
+
+// cellAttrs names the attributes reported for a cell.
+var cellAttrs = []struct {
+	attr terminal.Attr
+	name string
+}{
+	{terminal.AttrBold, "bold"},
+	{terminal.AttrItalic, "italic"},
+	{terminal.AttrUnderline, "underline"},
+	{terminal.AttrReverse, "reverse"},
+	{terminal.AttrBlink, "blink"},
+}
+
+func newCellsResult(s *session) cellsResult {
+	snap := s.term.Snapshot()
+	result := cellsResult{
+		SessionID:     s.id,
+		Cols:          snap.Cols,
+		Rows:          snap.Rows,
+		CursorRow:     snap.CursorRow,
+		CursorCol:     snap.CursorCol,
+		CursorVisible: snap.CursorVisible,
+		Cells:         make([][]cellJSON, len(snap.Cells)),
+	}
+	for row, cells := range snap.Cells {
+		result.Cells[row] = make([]cellJSON, len(cells))
+		for col, c := range cells {
+			ch := c.Char
+			if ch == 0 {
+				ch = ' '
+			}
+			cell := cellJSON{Char: string(ch), FG: hexColor(c.FG), BG: hexColor(c.BG)}
+			for _, a := range cellAttrs {
+				if c.Has(a.attr) {
+					cell.Attrs = append(cell.Attrs, a.name)
+				}
+			}
+			result.Cells[row][col] = cell
+		}
+	}
+	return result
+}
+
+func hexColor(c terminal.Color) string {
+	rgb := render.RGB(c)
+	return fmt.Sprintf("#%02x%02x%02x", rgb.R, rgb.G, rgb.B)
+}
+
+// queryCaptureOptions reads capture display options from the query string.
+func queryCaptureOptions(r *http.Request) (captureOptions, error) {
+	var opts captureOptions
+	q := r.URL.Query()
+	for name, dst := range map[string]*bool{
+		"show_cursor": &opts.ShowCursor,
+		"highlight":   &opts.Highlight,
+		"elements":    &opts.Elements,
+		"trim":        &opts.Trim,
+	} {
+		if v := q.Get(name); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return opts, fmt.Errorf("invalid %s %q", name, v)
+			}
+			*dst = b
+		}
+	}
+	return opts, nil
+}
+
+// decodeBody decodes a JSON request body (an empty body decodes as {}),
+// writing a 415 response unless it is sent as application/json and a 400
+// on failure.
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	if !isJSON(r) {
+		writeAPIError(w, http.StatusUnsupportedMediaType, errors.New("body must be sent with Content-Type application/json"))
+		return false
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return false
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		body = []byte("{}")
+	}
+	if err := decodeArgs(body, v); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return false
+	}
+	return true
+}
+
+// writeAPIResult writes an operation's result. An unknown session is a 404
+// and other failures without a result a 400. A failure that still has a
+// screen (a wait that timed out) is answered normally, with the error in
+// the capture's error field: the request itself was fine, and a 408 would
+// tell clients and proxies to send it again.
+func writeAPIResult[R any](w http.ResponseWriter, status int, result *R, err error) {
+	switch {
+	case err == nil:
+		writeJSON(w, status, result)
+	case errors.Is(err, errNoSession):
+		writeAPIError(w, http.StatusNotFound, err)
+	case result == nil:
+		writeAPIError(w, http.StatusBadRequest, err)
+	default:
+		c, ok := any(result).(*sessionCapture)
+		if !ok {
+			writeAPIError(w, http.StatusInternalServerError, err)
+			return
+		}
+		c.Error = err.Error()
+		writeJSON(w, status, result)
+	}
+}
+
+func writeAPIError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testToken = "secret"
+
+func newTestAPIServer(t *testing.T) *apiServer {
+	t.Helper()
+	hosts, err := allowedHosts("127.0.0.1:7777")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessions := newSessionManager()
+	t.Cleanup(sessions.stopAll)
+	return &apiServer{sessions: sessions, token: testToken, hosts: hosts}
+}
+
+// apiRequest sends a request to the server as a well-behaved client would,
+// after edit has had a chance to spoil it.
+func apiRequest(a *apiServer, method, path, body string, edit func(r *http.Request)) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r.Host = "127.0.0.1:7777"
+	r.Header.Set("Authorization", "Bearer "+testToken)
+	if body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	if edit != nil {
+		edit(r)
+	}
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	return w
+}
+
+func TestAllowedHosts(t *testing.T) {
+	hosts, err := allowedHosts("127.0.0.1:7777")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range []string{"127.0.0.1:7777", "localhost:7777", "[::1]:7777"} {
+		if !hosts[h] {
+			t.Errorf("%s not allowed for a loopback listener", h)
+		}
+	}
+	for _, h := range []string{"127.0.0.1:8080", "evil.example:7777", "localhost"} {
+		if hosts[h] {
+			t.Errorf("%s allowed for a loopback listener", h)
+		}
+	}
+	if _, err := allowedHosts("7777"); err == nil {
+		t.Error("allowedHosts without a port separator succeeded, want error")
+	}
+}
+
+func TestServeAuthorization(t *testing.T) {
+	a := newTestAPIServer(t)
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		edit   func(r *http.Request)
+		want   int
+	}{
+		{"authorized", "GET", "/sessions", "", nil, http.StatusOK},
+		{"same-origin browser", "GET", "/sessions", "", func(r *http.Request) {
+			r.Header.Set("Origin", "http://localhost:7777")
+		}, http.StatusOK},
+		{"missing token", "GET", "/sessions", "", func(r *http.Request) {
+			r.Header.Del("Authorization")
+		}, http.StatusUnauthorized},
+		{"wrong token", "GET", "/sessions", "", func(r *http.Request) {
+			r.Header.Set("Authorization", "Bearer guess")
+		}, http.StatusUnauthorized},
+		{"token without scheme", "GET", "/sessions", "", func(r *http.Request) {
+			r.Header.Set("Authorization", testToken)
+		}, http.StatusUnauthorized},
+		{"bad host", "GET", "/sessions", "", func(r *http.Request) {
+			r.Host = "evil.example:7777"
+		}, http.StatusForbidden},
+		{"cross-site origin", "GET", "/sessions", "", func(r *http.Request) {
+			r.Header.Set("Origin", "http://evil.example")
+		}, http.StatusForbidden},
+		{"text/plain body", "POST", "/sessions", `{"command": "true"}`, func(r *http.Request) {
+			r.Header.Set("Content-Type", "text/plain")
+		}, http.StatusUnsupportedMediaType},
+		{"form body", "POST", "/sessions/x/keys", "keys=enter", func(r *http.Request) {
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}, http.StatusUnsupportedMediaType},
+		{"unknown route", "GET", "/admin", "", nil, http.StatusNotFound},
+		{"unknown action", "POST", "/sessions/x/explode", "{}", nil, http.StatusNotFound},
+		{"too deep", "GET", "/sessions/x/keys/more", "", nil, http.StatusNotFound},
+		{"unknown session", "GET", "/sessions/x", "", nil, http.StatusNotFound},
+		{"wrong method on sessions", "PUT", "/sessions", "", nil, http.StatusMethodNotAllowed},
+		{"wrong method on a session", "POST", "/sessions/x", "{}", nil, http.StatusMethodNotAllowed},
+		{"wrong method on an action", "GET", "/sessions/x/keys", "", nil, http.StatusMethodNotAllowed},
+		{"wrong method on events", "POST", "/sessions/x/events", "{}", nil, http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := apiRequest(a, tt.method, tt.path, tt.body, tt.edit)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d (%s)", w.Code, tt.want, w.Body)
+			}
+			if tt.want == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") != "Bearer" {
+				t.Error("401 without WWW-Authenticate: Bearer")
+			}
+			if tt.want != http.StatusOK {
+				var body struct{ Error string }
+				if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.Error == "" {
+					t.Errorf("error body = %s, want {\"error\": ...}", w.Body)
+				}
+			}
+		})
+	}
+}
+
+func TestServeWaitTimeout(t *testing.T) {
+	a := newTestAPIServer(t)
+	w := apiRequest(a, "POST", "/sessions", `{"command": "sh", "args": ["-c", "echo ready; sleep 10"], "wait_for": "ready"}`, nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("start: status = %d (%s)", w.Code, w.Body)
+	}
+	var started sessionCapture
+	if err := json.Unmarshal(w.Body.Bytes(), &started); err != nil {
+		t.Fatal(err)
+	}
+
+	w = apiRequest(a, "POST", "/sessions/"+started.SessionID+"/wait", `{"text": "never", "timeout_ms": 100}`, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("wait: status = %d, want 200 (%s)", w.Code, w.Body)
+	}
+	var waited sessionCapture
+	if err := json.Unmarshal(w.Body.Bytes(), &waited); err != nil {
+		t.Fatal(err)
+	}
+	if waited.Error == "" || !strings.Contains(waited.Screen, "ready") {
+		t.Errorf("wait = error %q, screen %q; want an error and the screen", waited.Error, waited.Screen)
+	}
+}
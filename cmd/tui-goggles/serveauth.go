@@ -0,0 +1,107 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// serveTokenEnv presets the serve API's bearer token for serve (instead of
+// a random one) and for attach.
+const serveTokenEnv = "TUI_GOGGLES_TOKEN"
+
+// newServeToken returns a random bearer token for one serve run.
+func newServeToken() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// allowedHosts returns the Host (and Origin) values requests to a server
+// listening on addr may carry: addr itself, the loopback names when it
+// listens on loopback, and every local address when it listens on all
+// interfaces. Anything else is a page in a browser that has been pointed
+// at us through DNS rebinding.
+func allowedHosts(addr string) (map[string]bool, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	hosts := make(map[string]bool)
+	add := func(h string) {
+		hosts[strings.ToLower(net.JoinHostPort(h, port))] = true
+	}
+	if host != "" {
+		add(host)
+	}
+	ip := net.ParseIP(host)
+	all := host == "" || (ip != nil && ip.IsUnspecified())
+	if all || host == "localhost" || (ip != nil && ip.IsLoopback()) {
+		add("localhost")
+		add("127.0.0.1")
+		add("::1")
+	}
+	if all {
+		addrs, _ := net.InterfaceAddrs()
+		for _, a := range addrs {
+			if ipnet, ok := a.(*net.IPNet); ok {
+				add(ipnet.IP.String())
+			}
+		}
+		if name, err := os.Hostname(); err == nil {
+			add(name)
+		}
+	}
+	return hosts, nil
+}
+
+// isLoopback reports whether a listen address is only reachable from this
+// machine.
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return host == "localhost" || (ip != nil && ip.IsLoopback())
+}
+
+// authorize checks that a request comes from a client that was given the
+// token, and not from a web page: every request must name us in Host (and
+// Origin, if a browser sent one) and carry the bearer token.
+func (a *apiServer) authorize(w http.ResponseWriter, r *http.Request) bool {
+	if !a.hosts[strings.ToLower(r.Host)] {
+		writeAPIError(w, http.StatusForbidden, fmt.Errorf("host %q not allowed", r.Host))
+		return false
+	}
+	if origin := r.Header.Get("Origin"); origin != "" {
+		u, err := url.Parse(origin)
+		if err != nil || !a.hosts[strings.ToLower(u.Host)] {
+			writeAPIError(w, http.StatusForbidden, fmt.Errorf("origin %q not allowed", origin))
+			return false
+		}
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeAPIError(w, http.StatusUnauthorized, errors.New("missing or wrong bearer token"))
+		return false
+	}
+	return true
+}
+
+// isJSON reports whether a request says its body is JSON. Other types
+// (text/plain, forms) are what a cross-site form or fetch can send without
+// asking first, so they are refused.
+func isJSON(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "application/json"
+}
@@ -0,0 +1,488 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/your-username/tui-goggles/internal/terminal"
+)
+
+// Defaults for session operations, matching the CLI flags of the same name.
+const (
+	sessionDelay         = 500 * time.Millisecond
+	sessionStableTime    = 200 * time.Millisecond
+	sessionStableTimeout = 5 * time.Second
+	sessionInputDelay    = 50 * time.Millisecond
+)
+
+// sessionOptions describes the command a long-lived session runs.
+type sessionOptions struct {
+	Command  string   `json:"command"`
+	Args     []string `json:"args,omitempty"`
+	Cols     int      `json:"cols,omitempty"`
+	Rows     int      `json:"rows,omitempty"`
+	Env      []string `json:"env,omitempty"`
+	Cwd      string   `json:"cwd,omitempty"`
+	CleanEnv bool     `json:"clean_env,omitempty"`
+}
+
+// session is a terminal kept alive between requests, as used by the mcp and
+// serve commands.
+type session struct {
+	id      string
+	command string
+	args    []string
+	term    *terminal.Terminal
+	// home is the temporary HOME of a clean-environment session.
+	home string
+
+	// mu serializes operations on the session, so keys and waits from
+	// concurrent requests do not interleave.
+	mu sync.Mutex
+	// lastUsed is guarded by the manager's mu.
+	lastUsed time.Time
+}
+
+// errNoSession is returned for an unknown (or already stopped) session ID.
+var errNoSession = errors.New("no session")
+
+// sessionManager keeps track of the running sessions.
+type sessionManager struct {
+	mu       sync.Mutex
+	sessions map[string]*session
+}
+
+func newSessionManager() *sessionManager {
+	return &sessionManager{sessions: make(map[string]*session)}
+}
+
+// start runs a command in a new session.
+func (m *sessionManager) start(opts sessionOptions) (*session, error) {
+	if opts.Command == "" {
+		return nil, fmt.Errorf("no command specified")
+	}
+	if opts.Cols == 0 {
+		opts.Cols = 80
+	}
+	if opts.Rows == 0 {
+		opts.Rows = 24
+	}
+	if opts.Cwd != "" {
+		if info, err := os.Stat(opts.Cwd); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("cwd %q is not a directory", opts.Cwd)
+		}
+	}
+
+	termOpts := terminal.Options{
+		Rows: opts.Rows,
+		Cols: opts.Cols,
+		Env:  opts.Env,
+		Dir:  opts.Cwd,
+	}
+	home := ""
+	if opts.CleanEnv {
+		var err error
+		home, err = os.MkdirTemp("", "tui-goggles-home-")
+		if err != nil {
+			return nil, fmt.Errorf("creating temporary HOME: %w", err)
+		}
+		termOpts.CleanEnv = true
+		termOpts.Env = append(cleanEnv(home, opts.Cols, opts.Rows, nil), opts.Env...)
+	}
+
+	term, err := terminal.New(opts.Command, opts.Args, termOpts)
+	if err != nil {
+		if home != "" {
+			os.RemoveAll(home)
+		}
+		return nil, fmt.Errorf("failed to create terminal: %w", err)
+	}
+
+	s := &session{
+		id:       newSessionID(),
+		command:  opts.Command,
+		args:     opts.Args,
+		term:     term,
+		home:     home,
+		lastUsed: time.Now(),
+	}
+	m.mu.Lock()
+	m.sessions[s.id] = s
+	m.mu.Unlock()
+	return s, nil
+}
+
+// get returns a running session and marks it as used.
+func (m *sessionManager) get(id string) (*session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[id]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", errNoSession, id)
+	}
+	s.lastUsed = time.Now()
+	return s, nil
+}
+
+// touch marks a session as used, if it still exists.
+func (m *sessionManager) touch(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok := m.sessions[id]; ok {
+		s.lastUsed = time.Now()
+	}
+}
+
+// reap stops the sessions that have not been used for idle and are not in
+// the middle of an operation, returning their IDs.
+func (m *sessionManager) reap(idle time.Duration) []string {
+	var stale []string
+	m.mu.Lock()
+	for id, s := range m.sessions {
+		if time.Since(s.lastUsed) < idle {
+			continue
+		}
+		if !s.mu.TryLock() {
+			continue
+		}
+		s.mu.Unlock()
+		stale = append(stale, id)
+	}
+	m.mu.Unlock()
+
+	for _, id := range stale {
+		_, _ = m.stop(id, terminal.ShutdownPolicy{TermWait: time.Second})
+	}
+	return stale
+}
+
+// list returns the running sessions in no particular order.
+func (m *sessionManager) list() []*session {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sessions := make([]*session, 0, len(m.sessions))
+	for _, s := range m.sessions {
+		sessions = append(sessions, s)
+	}
+	return sessions
+}
+
+// stop ends a session's command (see terminal.Shutdown) and forgets it.
+func (m *sessionManager) stop(id string, policy terminal.ShutdownPolicy) (terminal.ShutdownResult, error) {
+	m.mu.Lock()
+	s, ok := m.sessions[id]
+	delete(m.sessions, id)
+	m.mu.Unlock()
+	if !ok {
+		return terminal.ShutdownResult{}, fmt.Errorf("%w %q", errNoSession, id)
+	}
+	return s.close(policy), nil
+}
+
+// stopAll ends every session, for when the server exits.
+func (m *sessionManager) stopAll() {
+	for _, s := range m.list() {
+		_, _ = m.stop(s.id, terminal.ShutdownPolicy{TermWait: time.Second})
+	}
+}
+
+func (s *session) close(policy terminal.ShutdownPolicy) terminal.ShutdownResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := s.term.Shutdown(policy)
+	s.term.Close()
+	if s.home != "" {
+		os.RemoveAll(s.home)
+	}
+	return result
+}
+
+// capture takes a capture of the session's screen, as the CLI would with
+// the given display options.
+func (s *session) capture(opts captureOptions) CaptureResult {
+	return captureScreen(s.term, s.command, s.args, opts.config(), nil)
+}
+
+// captureOptions are the display options of a session capture.
+type captureOptions struct {
+	ShowCursor bool `json:"show_cursor,omitempty"`
+	Highlight  bool `json:"highlight,omitempty"`
+	Elements   bool `json:"elements,omitempty"`
+	Trim       bool `json:"trim,omitempty"`
+}
+
+func (o captureOptions) config() config {
+	return config{
+		showCursor: o.ShowCursor,
+		highlight:  o.Highlight,
+		elements:   o.Elements,
+		trim:       o.Trim,
+		stableTime: sessionStableTime,
+	}
+}
+
+// sessionCapture is a capture labelled with the session it was taken from.
+type sessionCapture struct {
+	SessionID string `json:"session_id"`
+	Running   bool   `json:"running"`
+	// Error is set by the serve API when the operation failed after all
+	// (a wait that timed out).
+	Error string `json:"error,omitempty"`
+	CaptureResult
+}
+
+func (s *session) result(capture CaptureResult) sessionCapture {
+	return sessionCapture{SessionID: s.id, Running: s.term.IsRunning(), CaptureResult: capture}
+}
+
+// summary describes the session for listings.
+func (s *session) summary() sessionSummary {
+	cols, rows := s.term.Size()
+	return sessionSummary{SessionID: s.id, Command: s.command, Running: s.term.IsRunning(), Cols: cols, Rows: rows}
+}
+
+// newSessionID returns a random session identifier.
+func newSessionID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// millisOr converts a millisecond argument to a duration, using def for 0.
+func millisOr(ms int, def time.Duration) time.Duration {
+	if ms <= 0 {
+		return def
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// Arguments of the session operations, shared by the mcp tools and the
+// serve API.
+
+type startSessionArgs struct {
+	sessionOptions
+	captureOptions
+	DelayMs   int    `json:"delay_ms,omitempty"`
+	WaitFor   string `json:"wait_for,omitempty"`
+	TimeoutMs int    `json:"timeout_ms,omitempty"`
+}
+
+type sendKeysArgs struct {
+	SessionID string `json:"session_id"`
+	captureOptions
+	Keys         string `json:"keys,omitempty"`
+	Text         string `json:"text,omitempty"`
+	InputDelayMs int    `json:"input_delay_ms,omitempty"`
+	TimeoutMs    int    `json:"timeout_ms,omitempty"`
+	// NoWait returns right after sending, for interactive use.
+	NoWait bool `json:"no_wait,omitempty"`
+}
+
+type captureArgs struct {
+	SessionID string `json:"session_id"`
+	captureOptions
+}
+
+type waitForArgs struct {
+	SessionID string `json:"session_id"`
+	captureOptions
+	Text      string `json:"text,omitempty"`
+	Stable    bool   `json:"stable,omitempty"`
+	Exit      bool   `json:"exit,omitempty"`
+	TimeoutMs int    `json:"timeout_ms,omitempty"`
+}
+
+type resizeArgs struct {
+	SessionID string `json:"session_id"`
+	captureOptions
+	Cols      int `json:"cols"`
+	Rows      int `json:"rows"`
+	TimeoutMs int `json:"timeout_ms,omitempty"`
+}
+
+type stopSessionArgs struct {
+	SessionID    string `json:"session_id"`
+	ShutdownKeys string `json:"shutdown_keys,omitempty"`
+	TermWaitMs   int    `json:"term_wait_ms,omitempty"`
+}
+
+// sessionSummary describes a session in listings.
+type sessionSummary struct {
+	SessionID string `json:"session_id"`
+	Command   string `json:"command"`
+	Running   bool   `json:"running"`
+	Cols      int    `json:"cols"`
+	Rows      int    `json:"rows"`
+}
+
+type sessionList struct {
+	Sessions []sessionSummary `json:"sessions"`
+}
+
+// startSession starts a session and returns its first screen: as the CLI
+// does, the app is given time to render and then to settle (or to show
+// WaitFor).
+func (m *sessionManager) startSession(args startSessionArgs) (*sessionCapture, error) {
+	s, err := m.start(args.sessionOptions)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.term.Delay(millisOr(args.DelayMs, sessionDelay))
+	timeout := millisOr(args.TimeoutMs, sessionStableTimeout)
+	var stableErr error
+	if args.WaitFor != "" {
+		err = s.term.WaitForText(args.WaitFor, timeout)
+	} else {
+		stableErr = s.term.WaitForStable(timeout, sessionStableTime)
+	}
+	result := s.result(s.capture(args.captureOptions))
+	if args.WaitFor == "" {
+		result.Stable = stableErr == nil
+	}
+	return &result, err
+}
+
+// sendSessionKeys sends keys (a -keys spec) and then literal text, and
+// returns the screen once it settles (or at once with NoWait).
+func (m *sessionManager) sendSessionKeys(args sendKeysArgs) (*sessionCapture, error) {
+	if args.Keys == "" && args.Text == "" {
+		return nil, errors.New("give keys or text to send")
+	}
+	s, err := m.get(args.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	inputDelay := millisOr(args.InputDelayMs, sessionInputDelay)
+	if args.NoWait {
+		inputDelay = 0
+	}
+	if args.Keys != "" {
+		if err := sendKeys(s.term, args.Keys, inputDelay, nil); err != nil {
+			return nil, fmt.Errorf("sending keys: %w", err)
+		}
+	}
+	if args.Text != "" {
+		if err := s.term.SendKeys(args.Text); err != nil {
+			return nil, fmt.Errorf("sending text: %w", err)
+		}
+		s.term.Delay(inputDelay)
+	}
+	if args.NoWait {
+		result := s.result(s.capture(args.captureOptions))
+		return &result, nil
+	}
+	stableErr := s.term.WaitForStable(millisOr(args.TimeoutMs, sessionStableTimeout), sessionStableTime)
+	result := s.result(s.capture(args.captureOptions))
+	result.Stable = stableErr == nil
+	return &result, nil
+}
+
+// captureSession returns the current screen without waiting.
+func (m *sessionManager) captureSession(args captureArgs) (*sessionCapture, error) {
+	s, err := m.get(args.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := s.result(s.capture(args.captureOptions))
+	return &result, nil
+}
+
+// waitSession waits for text, a stable screen or the command's exit. The
+// screen is returned even when the wait fails.
+func (m *sessionManager) waitSession(args waitForArgs) (*sessionCapture, error) {
+	if args.Text == "" && !args.Stable && !args.Exit {
+		return nil, errors.New("give text, stable or exit to wait for")
+	}
+	s, err := m.get(args.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	timeout := millisOr(args.TimeoutMs, sessionStableTimeout)
+	switch {
+	case args.Text != "":
+		err = s.term.WaitForText(args.Text, timeout)
+	case args.Exit:
+		_, err = s.term.WaitExit(timeout)
+	default:
+		err = s.term.WaitForStable(timeout, sessionStableTime)
+	}
+	result := s.result(s.capture(args.captureOptions))
+	if args.Text == "" && !args.Exit {
+		result.Stable = err == nil
+	}
+	return &result, err
+}
+
+// resizeSession resizes the terminal and returns the screen once the app
+// has redrawn.
+func (m *sessionManager) resizeSession(args resizeArgs) (*sessionCapture, error) {
+	if args.Cols <= 0 || args.Rows <= 0 {
+		return nil, errors.New("cols and rows must be positive")
+	}
+	s, err := m.get(args.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.term.Resize(args.Cols, args.Rows); err != nil {
+		return nil, fmt.Errorf("resizing: %w", err)
+	}
+	s.term.Delay(sessionInputDelay)
+	stableErr := s.term.WaitForStable(millisOr(args.TimeoutMs, sessionStableTimeout), sessionStableTime)
+	result := s.result(s.capture(args.captureOptions))
+	result.Stable = stableErr == nil
+	return &result, nil
+}
+
+// stopSession ends the session's command and returns the last screen with
+// the shutdown result.
+func (m *sessionManager) stopSession(args stopSessionArgs) (*sessionCapture, error) {
+	s, err := m.get(args.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	final := s.capture(captureOptions{})
+	s.mu.Unlock()
+
+	shutdown, err := m.stop(args.SessionID, terminal.ShutdownPolicy{
+		Keys:     keySequence(args.ShutdownKeys),
+		KeysWait: time.Second,
+		TermWait: millisOr(args.TermWaitMs, time.Second),
+	})
+	if err != nil {
+		return nil, err
+	}
+	final.Shutdown = &shutdown
+	return &sessionCapture{SessionID: args.SessionID, CaptureResult: final}, nil
+}
+
+// listSessions lists the running sessions by ID.
+func (m *sessionManager) listSessions(struct{}) (*sessionList, error) {
+	sessions := m.list()
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].id < sessions[j].id })
+	list := &sessionList{Sessions: []sessionSummary{}}
+	for _, s := range sessions {
+		list.Sessions = append(list.Sessions, s.summary())
+	}
+	return list, nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/your-username/tui-goggles/internal/terminal"
+)
+
+// openChildFiles opens the files given by -stdin-file, -stderr-file and
+// -extra-fd and sets them in opts. The returned function closes them; the
+// command keeps its own copies.
+func openChildFiles(cfg config, opts *terminal.Options) (func(), error) {
+	var opened []*os.File
+	closeFiles := func() {
+		for _, f := range opened {
+			f.Close()
+		}
+	}
+	fail := func(err error) (func(), error) {
+		closeFiles()
+		return nil, err
+	}
+
+	switch cfg.stdinFile {
+	case "":
+	case "-":
+		opts.Stdin = os.Stdin
+	default:
+		f, err := os.Open(cfg.stdinFile)
+		if err != nil {
+			return fail(fmt.Errorf("-stdin-file: %w", err))
+		}
+		opened = append(opened, f)
+		opts.Stdin = f
+	}
+
+	if cfg.stderrFile != "" {
+		f, err := os.Create(cfg.stderrFile)
+		if err != nil {
+			return fail(fmt.Errorf("-stderr-file: %w", err))
+		}
+		opened = append(opened, f)
+		opts.Stderr = f
+	}
+
+	for _, spec := range cfg.extraFds {
+		// A number passes through one of our own open descriptors (a copy
+		// of it, so closing the file does not close ours); anything else is
+		// a file
+		if fd, err := strconv.Atoi(spec); err == nil {
+			f, err := dupFile(fd)
+			if err != nil {
+				return fail(fmt.Errorf("-extra-fd: descriptor %d: %w", fd, err))
+			}
+			opened = append(opened, f)
+			opts.ExtraFiles = append(opts.ExtraFiles, f)
+			continue
+		}
+		path, flags := extraFileMode(spec)
+		f, err := os.OpenFile(path, flags, 0644)
+		if err != nil {
+			return fail(fmt.Errorf("-extra-fd: %w", err))
+		}
+		opened = append(opened, f)
+		opts.ExtraFiles = append(opts.ExtraFiles, f)
+	}
+
+	return closeFiles, nil
+}
+
+// extraFileMode splits an -extra-fd path into the file and the mode to open
+// it with: "<path" reads an existing file, ">>path" appends to one, and a
+// plain path (or ">path") is written from the start, replacing what was
+// there.
+func extraFileMode(spec string) (string, int) {
+	switch {
+	case strings.HasPrefix(spec, "<"):
+		return spec[1:], os.O_RDONLY
+	case strings.HasPrefix(spec, ">>"):
+		return spec[2:], os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	default:
+		return strings.TrimPrefix(spec, ">"), os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+func dupFile(fd int) (*os.File, error) {
+	return nil, errors.New("passing descriptors is only supported on Unix")
+}
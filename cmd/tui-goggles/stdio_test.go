@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/your-username/tui-goggles/internal/terminal"
+)
+
+func TestExtraFileMode(t *testing.T) {
+	tests := []struct {
+		spec      string
+		wantPath  string
+		wantFlags int
+	}{
+		{"out.log", "out.log", os.O_WRONLY | os.O_CREATE | os.O_TRUNC},
+		{">out.log", "out.log", os.O_WRONLY | os.O_CREATE | os.O_TRUNC},
+		{">>out.log", "out.log", os.O_WRONLY | os.O_CREATE | os.O_APPEND},
+		{"<in.txt", "in.txt", os.O_RDONLY},
+		{"dir/a>b", "dir/a>b", os.O_WRONLY | os.O_CREATE | os.O_TRUNC},
+	}
+	for _, tt := range tests {
+		path, flags := extraFileMode(tt.spec)
+		if path != tt.wantPath || flags != tt.wantFlags {
+			t.Errorf("extraFileMode(%q) = %q, %#x, want %q, %#x", tt.spec, path, flags, tt.wantPath, tt.wantFlags)
+		}
+	}
+}
+
+func TestOpenChildFilesExtraFd(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.txt")
+	if err := os.WriteFile(input, []byte("in"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	own, err := os.Create(filepath.Join(dir, "own.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer own.Close()
+
+	cfg := config{extraFds: []string{
+		strconv.Itoa(int(own.Fd())),
+		"<" + input,
+		">>" + filepath.Join(dir, "append.log"),
+		filepath.Join(dir, "out.log"),
+	}}
+	var opts terminal.Options
+	closeFiles, err := openChildFiles(cfg, &opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(opts.ExtraFiles) != len(cfg.extraFds) {
+		t.Fatalf("ExtraFiles = %d files, want %d", len(opts.ExtraFiles), len(cfg.extraFds))
+	}
+	if opts.ExtraFiles[0].Fd() == own.Fd() {
+		t.Error("a passed descriptor is wrapped as is, not copied")
+	}
+
+	// Neither closing the copies nor collecting them closes our descriptor
+	closeFiles()
+	opts.ExtraFiles = nil
+	runtime.GC()
+	runtime.GC()
+	if _, err := own.WriteString("still open"); err != nil {
+		t.Errorf("passed descriptor was closed: %v", err)
+	}
+
+	for _, spec := range []string{"-1", "9999", "<" + filepath.Join(dir, "missing")} {
+		var opts terminal.Options
+		if _, err := openChildFiles(config{extraFds: []string{spec}}, &opts); err == nil {
+			t.Errorf("-extra-fd %s succeeded, want error", spec)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+//go:build unix
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// dupFile returns a copy of one of our open descriptors as a file. Closing
+// the copy, or the garbage collector finalizing it, leaves the original
+// open.
+func dupFile(fd int) (*os.File, error) {
+	if fd < 0 {
+		return nil, errors.New("invalid descriptor")
+	}
+	dup, err := syscall.Dup(fd)
+	if err != nil {
+		return nil, err
+	}
+	syscall.CloseOnExec(dup)
+	return os.NewFile(uintptr(dup), fmt.Sprintf("fd %d", fd)), nil
+}
@@ -5,4 +5,7 @@ go 1.21
 require (
 	github.com/creack/pty v1.1.21
 	github.com/hinshun/vt10x v0.0.0-20220301184237-5011da428d02
+	golang.org/x/image v0.15.0
 )
+
+require golang.org/x/text v0.14.0 // indirect
@@ -0,0 +1,183 @@
+package elements
+
+import (
+	"strings"
+
+	"github.com/your-username/tui-goggles/internal/terminal"
+)
+
+const (
+	// statusBarRatio is the fraction of a row that must be styled for it to
+	// count as a status bar.
+	statusBarRatio = 0.6
+	// tabBarRows is how many rows from the top are searched for tab bars.
+	tabBarRows = 3
+	// maxTabLabel is the longest label still considered a tab.
+	maxTabLabel = 24
+)
+
+// detectStatusBars reports the first and last screen rows when they are
+// drawn with a styled background. The last row also counts when it is set
+// apart from the content above it by a blank row.
+func detectStatusBars(g *grid) []Element {
+	var bars []Element
+
+	last := g.rows() - 1
+	for _, y := range []int{0, last} {
+		if y < 0 || g.claimed[y] || g.blank(y) || g.isBorderRow(y) {
+			continue
+		}
+		separated := y == last && y > 0 && g.blank(y-1)
+		if g.styledRatio(y) < statusBarRatio && !separated {
+			continue
+		}
+		g.claimed[y] = true
+		bars = append(bars, Element{
+			Kind:   KindStatusBar,
+			Row:    y,
+			Col:    0,
+			Width:  g.cols(),
+			Height: 1,
+			Text:   strings.TrimSpace(g.snap.Line(y)),
+		})
+		if g.rows() == 1 {
+			break
+		}
+	}
+
+	return bars
+}
+
+// isBorderRow reports whether the row is the top or bottom edge of a panel.
+func (g *grid) isBorderRow(row int) bool {
+	for _, p := range g.panels {
+		if row == p.Row || row == p.Row+p.Height-1 {
+			return true
+		}
+	}
+	return false
+}
+
+// tabLabel is a run of text on a candidate tab bar row.
+type tabLabel struct {
+	text     string
+	col      int
+	selected bool
+}
+
+// detectTabBars looks near the top of the screen for rows made of short
+// labels where some, but not all, labels are drawn in a distinct style.
+func detectTabBars(g *grid) []Element {
+	var bars []Element
+
+	for y := 0; y < tabBarRows && y < g.rows(); y++ {
+		if g.blank(y) || g.isBorderRow(y) {
+			continue
+		}
+
+		labels := g.splitLabels(y)
+		if len(labels) < 2 {
+			continue
+		}
+
+		selected := 0
+		valid := true
+		for _, l := range labels {
+			if len([]rune(l.text)) > maxTabLabel {
+				valid = false
+				break
+			}
+			if l.selected {
+				selected++
+			}
+		}
+		if !valid || selected == 0 || selected == len(labels) {
+			continue
+		}
+
+		items := make([]Item, len(labels))
+		for i, l := range labels {
+			items[i] = Item{Text: l.text, Row: y, Col: l.col, Selected: l.selected}
+		}
+		last := labels[len(labels)-1]
+
+		g.claimed[y] = true
+		bars = append(bars, Element{
+			Kind:   KindTabBar,
+			Row:    y,
+			Col:    labels[0].col,
+			Width:  last.col + len([]rune(last.text)) - labels[0].col,
+			Height: 1,
+			Items:  items,
+		})
+	}
+
+	return bars
+}
+
+// splitLabels breaks a row into labels at separator characters, runs of two
+// or more spaces, and changes of style.
+func (g *grid) splitLabels(row int) []tabLabel {
+	var labels []tabLabel
+
+	start := -1
+	var cur []terminal.Cell
+
+	flush := func() {
+		if start < 0 {
+			return
+		}
+		var sb strings.Builder
+		styledCells, textCells := 0, 0
+		for _, c := range cur {
+			sb.WriteRune(c.Char)
+			if c.Char != ' ' {
+				textCells++
+				if distinct(c) {
+					styledCells++
+				}
+			}
+		}
+		raw := sb.String()
+		text := strings.TrimSpace(raw)
+		if text != "" {
+			lead := len([]rune(raw)) - len([]rune(strings.TrimLeft(raw, " ")))
+			labels = append(labels, tabLabel{
+				text:     text,
+				col:      start + lead,
+				selected: styledCells*2 > textCells,
+			})
+		}
+		start = -1
+		cur = nil
+	}
+
+	for x := 0; x < g.cols(); x++ {
+		c := g.cell(row, x)
+
+		if c.Char == '|' || isOneOf(c.Char, verticalEdges) {
+			flush()
+			continue
+		}
+		if c.Char == ' ' && g.char(row, x+1) == ' ' && !distinct(c) {
+			flush()
+			continue
+		}
+		if start >= 0 && distinct(c) != distinct(cur[len(cur)-1]) {
+			flush()
+		}
+		if start < 0 {
+			start = x
+		}
+		cur = append(cur, c)
+	}
+	flush()
+
+	return labels
+}
+
+// distinct reports whether a cell is drawn in a style that marks it as
+// active or selected.
+func distinct(c terminal.Cell) bool {
+	return styled(c) || c.Has(terminal.AttrBold) || c.Has(terminal.AttrUnderline)
+}
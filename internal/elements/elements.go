@@ -0,0 +1,121 @@
+// Package elements finds common TUI structures in a captured screen grid.
+//
+// Detection is heuristic: it looks for box-drawing borders, highlighted or
+// bulleted list rows, styled status and tab bars, and the input field under
+// the cursor. Coordinates are 0-indexed cells of the virtual screen.
+package elements
+
+import (
+	"strings"
+
+	"github.com/your-username/tui-goggles/internal/terminal"
+)
+
+// Element kinds.
+const (
+	KindPanel     = "panel"
+	KindList      = "list"
+	KindStatusBar = "status_bar"
+	KindTabBar    = "tab_bar"
+	KindInput     = "input"
+)
+
+// Element is a UI structure found on the screen.
+type Element struct {
+	Kind   string `json:"kind"`
+	Row    int    `json:"row"`
+	Col    int    `json:"col"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Title  string `json:"title,omitempty"`
+	Label  string `json:"label,omitempty"`
+	Text   string `json:"text,omitempty"`
+	Items  []Item `json:"items,omitempty"`
+}
+
+// Item is an entry of a list or tab bar.
+type Item struct {
+	Text     string `json:"text"`
+	Row      int    `json:"row"`
+	Col      int    `json:"col"`
+	Selected bool   `json:"selected,omitempty"`
+}
+
+// Detect runs every detector over the snapshot and returns the elements
+// found, panels first.
+func Detect(snap terminal.Snapshot) []Element {
+	g := newGrid(snap)
+
+	g.panels = detectPanels(g)
+
+	var found []Element
+	found = append(found, g.panels...)
+	// Tab bars are detected before status bars so a styled top row with one
+	// distinct label is reported as tabs rather than a status bar.
+	found = append(found, detectTabBars(g)...)
+	found = append(found, detectStatusBars(g)...)
+	found = append(found, detectLists(g)...)
+	if input, ok := detectInput(g); ok {
+		found = append(found, input)
+	}
+	return found
+}
+
+// grid wraps a snapshot with helpers shared by the detectors.
+type grid struct {
+	snap   terminal.Snapshot
+	panels []Element
+	// claimed marks rows already attributed to a status or tab bar so the
+	// list detector does not report them again.
+	claimed map[int]bool
+}
+
+func newGrid(snap terminal.Snapshot) *grid {
+	return &grid{snap: snap, claimed: make(map[int]bool)}
+}
+
+func (g *grid) rows() int { return g.snap.Rows }
+func (g *grid) cols() int { return g.snap.Cols }
+
+func (g *grid) char(row, col int) rune {
+	if row < 0 || row >= len(g.snap.Cells) || col < 0 || col >= len(g.snap.Cells[row]) {
+		return ' '
+	}
+	return g.snap.Cells[row][col].Char
+}
+
+func (g *grid) cell(row, col int) terminal.Cell {
+	return g.snap.Cells[row][col]
+}
+
+// text returns the cells [from, to) of a row as a string.
+func (g *grid) text(row, from, to int) string {
+	var sb strings.Builder
+	for x := from; x < to; x++ {
+		sb.WriteRune(g.char(row, x))
+	}
+	return sb.String()
+}
+
+func (g *grid) blank(row int) bool {
+	return strings.TrimSpace(g.snap.Line(row)) == ""
+}
+
+// styled reports whether a cell stands out from the default rendition.
+func styled(c terminal.Cell) bool {
+	return c.Has(terminal.AttrReverse) || c.BG != terminal.DefaultBG
+}
+
+// styledRatio returns the fraction of cells in a row that are styled.
+func (g *grid) styledRatio(row int) float64 {
+	if g.cols() == 0 {
+		return 0
+	}
+	n := 0
+	for x := 0; x < g.cols(); x++ {
+		if styled(g.cell(row, x)) {
+			n++
+		}
+	}
+	return float64(n) / float64(g.cols())
+}
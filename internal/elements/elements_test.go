@@ -0,0 +1,344 @@
+package elements
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/your-username/tui-goggles/internal/terminal"
+)
+
+// fixture describes a small screen: its text rows, and optionally a style
+// row for each, where r marks a reverse-video cell, b a bold one and g one
+// with a colored background.
+type fixture struct {
+	cols   int
+	lines  []string
+	styles []string
+	// cursor is the visible cursor position as {row, col}, or nil.
+	cursor []int
+}
+
+func (f fixture) snapshot() terminal.Snapshot {
+	snap := terminal.Snapshot{Cols: f.cols, Rows: len(f.lines)}
+	for y, line := range f.lines {
+		runes := []rune(line)
+		var style []rune
+		if y < len(f.styles) {
+			style = []rune(f.styles[y])
+		}
+		row := make([]terminal.Cell, f.cols)
+		for x := range row {
+			c := terminal.Cell{Char: ' ', FG: terminal.DefaultFG, BG: terminal.DefaultBG}
+			if x < len(runes) {
+				c.Char = runes[x]
+			}
+			if x < len(style) {
+				switch style[x] {
+				case 'r':
+					c.Attr |= terminal.AttrReverse
+				case 'b':
+					c.Attr |= terminal.AttrBold
+				case 'g':
+					c.BG = 4
+				}
+			}
+			row[x] = c
+		}
+		snap.Cells = append(snap.Cells, row)
+	}
+	if f.cursor != nil {
+		snap.CursorRow, snap.CursorCol, snap.CursorVisible = f.cursor[0], f.cursor[1], true
+	}
+	return snap
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name   string
+		screen fixture
+		want   []Element
+	}{
+		{
+			name:   "empty screen",
+			screen: fixture{cols: 20, lines: []string{"", "", ""}},
+			want:   nil,
+		},
+		{
+			name: "panel with title",
+			screen: fixture{cols: 20, lines: []string{
+				"┌─ Files ──────┐",
+				"│ main.go      │",
+				"│ README.md    │",
+				"└──────────────┘",
+			}},
+			want: []Element{
+				{Kind: KindPanel, Row: 0, Col: 0, Width: 16, Height: 4, Title: "Files"},
+			},
+		},
+		{
+			name: "nested panels",
+			screen: fixture{cols: 20, lines: []string{
+				"╭─[ Outer ]───────╮",
+				"│ ┌─ Inner ─┐     │",
+				"│ └─────────┘     │",
+				"╰─────────────────╯",
+			}},
+			want: []Element{
+				{Kind: KindPanel, Row: 0, Col: 0, Width: 19, Height: 4, Title: "Outer"},
+				{Kind: KindPanel, Row: 1, Col: 2, Width: 11, Height: 2, Title: "Inner"},
+			},
+		},
+		{
+			name: "pointer-marked list",
+			screen: fixture{cols: 20, lines: []string{
+				"  Pick a fruit:",
+				"  > Apple",
+				"    Banana",
+				"    Cherry",
+			}},
+			want: []Element{
+				{Kind: KindList, Row: 1, Col: 4, Width: 6, Height: 3, Items: []Item{
+					{Text: "Apple", Row: 1, Col: 4, Selected: true},
+					{Text: "Banana", Row: 2, Col: 4},
+					{Text: "Cherry", Row: 3, Col: 4},
+				}},
+			},
+		},
+		{
+			name: "highlighted list row",
+			screen: fixture{cols: 20,
+				lines:  []string{" Open", " Save", " Quit"},
+				styles: []string{"", " rrrr", ""},
+			},
+			want: []Element{
+				{Kind: KindList, Row: 0, Col: 1, Width: 4, Height: 3, Items: []Item{
+					{Text: "Open", Row: 0, Col: 1},
+					{Text: "Save", Row: 1, Col: 1, Selected: true},
+					{Text: "Quit", Row: 2, Col: 1},
+				}},
+			},
+		},
+		{
+			name: "checkbox list",
+			screen: fixture{cols: 20, lines: []string{
+				"[x] Lint",
+				"[ ] Test",
+				"[ ] Deploy",
+			}},
+			want: []Element{
+				{Kind: KindList, Row: 0, Col: 4, Width: 6, Height: 3, Items: []Item{
+					{Text: "Lint", Row: 0, Col: 4},
+					{Text: "Test", Row: 1, Col: 4},
+					{Text: "Deploy", Row: 2, Col: 4},
+				}},
+			},
+		},
+		{
+			name: "radio list",
+			screen: fixture{cols: 20, lines: []string{
+				"( ) Small",
+				"(*) Medium",
+			}},
+			want: []Element{
+				{Kind: KindList, Row: 0, Col: 4, Width: 6, Height: 2, Items: []Item{
+					{Text: "Small", Row: 0, Col: 4},
+					{Text: "Medium", Row: 1, Col: 4},
+				}},
+			},
+		},
+		{
+			name: "list inside a panel",
+			screen: fixture{cols: 20, lines: []string{
+				"┌──────────┐",
+				"│ • one    │",
+				"│ • two    │",
+				"└──────────┘",
+			}},
+			want: []Element{
+				{Kind: KindPanel, Row: 0, Col: 0, Width: 12, Height: 4},
+				{Kind: KindList, Row: 1, Col: 4, Width: 3, Height: 2, Items: []Item{
+					{Text: "one", Row: 1, Col: 4},
+					{Text: "two", Row: 2, Col: 4},
+				}},
+			},
+		},
+		{
+			name: "status bar",
+			screen: fixture{cols: 12,
+				lines:  []string{"text", "more text", "NORMAL  1:1"},
+				styles: []string{"", "", "gggggggggggg"},
+			},
+			want: []Element{
+				{Kind: KindStatusBar, Row: 2, Col: 0, Width: 12, Height: 1, Text: "NORMAL  1:1"},
+			},
+		},
+		{
+			name: "tab bar",
+			screen: fixture{cols: 24,
+				lines:  []string{" Files | Edit | View", "content"},
+				styles: []string{"         rrrr"},
+			},
+			want: []Element{
+				{Kind: KindTabBar, Row: 0, Col: 1, Width: 19, Height: 1, Items: []Item{
+					{Text: "Files", Row: 0, Col: 1},
+					{Text: "Edit", Row: 0, Col: 9, Selected: true},
+					{Text: "View", Row: 0, Col: 16},
+				}},
+			},
+		},
+		{
+			name:   "blank row before the last row",
+			screen: fixture{cols: 12, lines: []string{"text", "", "NORMAL  1:1"}},
+			want: []Element{
+				{Kind: KindStatusBar, Row: 2, Col: 0, Width: 12, Height: 1, Text: "NORMAL  1:1"},
+			},
+		},
+		{
+			name: "tab bar marked in bold",
+			screen: fixture{cols: 20,
+				lines:  []string{"one  two  three", "content"},
+				styles: []string{"     bbb"},
+			},
+			want: []Element{
+				{Kind: KindTabBar, Row: 0, Col: 0, Width: 15, Height: 1, Items: []Item{
+					{Text: "one", Row: 0, Col: 0},
+					{Text: "two", Row: 0, Col: 5, Selected: true},
+					{Text: "three", Row: 0, Col: 10},
+				}},
+			},
+		},
+		{
+			name: "fully styled top row is a status bar",
+			screen: fixture{cols: 12,
+				lines:  []string{" my-app v1.0", "content"},
+				styles: []string{"gggggggggggg"},
+			},
+			want: []Element{
+				{Kind: KindStatusBar, Row: 0, Col: 0, Width: 12, Height: 1, Text: "my-app v1.0"},
+			},
+		},
+		{
+			name: "input after a label",
+			screen: fixture{cols: 20,
+				lines:  []string{"Name: bob"},
+				cursor: []int{0, 9},
+			},
+			want: []Element{
+				{Kind: KindInput, Row: 0, Col: 6, Width: 14, Height: 1, Label: "Name:", Text: "bob"},
+			},
+		},
+		{
+			name: "input in a titled box",
+			screen: fixture{cols: 20,
+				lines: []string{
+					"┌─ Search ─┐",
+					"│foo       │",
+					"└──────────┘",
+				},
+				cursor: []int{1, 4},
+			},
+			want: []Element{
+				{Kind: KindPanel, Row: 0, Col: 0, Width: 12, Height: 3, Title: "Search"},
+				{Kind: KindInput, Row: 1, Col: 1, Width: 10, Height: 1, Label: "Search", Text: "foo"},
+			},
+		},
+
+		// False positives
+		{
+			name: "prose mentioning checkboxes",
+			screen: fixture{cols: 40, lines: []string{
+				"Press [x] to close the window, or",
+				"leave ( ) empty to keep the default.",
+			}},
+			want: nil,
+		},
+		{
+			name: "prose lines starting with different markers",
+			screen: fixture{cols: 40, lines: []string{
+				"[x] marks the spot, the map said.",
+				"( ) stays blank on the form.",
+			}},
+			want: nil,
+		},
+		{
+			name: "aligned prose",
+			screen: fixture{cols: 40, lines: []string{
+				"  The quick brown fox",
+				"  jumps over the lazy dog.",
+			}},
+			want: nil,
+		},
+		{
+			name: "every row highlighted",
+			screen: fixture{cols: 10,
+				lines:  []string{"  one", "  two", "  three"},
+				styles: []string{"  rrr", "  rrr", "  rrrrr"},
+			},
+			want: nil,
+		},
+		{
+			name: "vertical bars without corners",
+			screen: fixture{cols: 20, lines: []string{
+				"a │ b",
+				"c │ d",
+			}},
+			want: nil,
+		},
+		{
+			name: "box with a broken edge",
+			screen: fixture{cols: 20, lines: []string{
+				"┌────┐",
+				"│    x",
+				"└────┘",
+			}},
+			want: nil,
+		},
+		{
+			name: "bare cursor after output",
+			screen: fixture{cols: 20,
+				lines:  []string{"build finished", ""},
+				cursor: []int{1, 0},
+			},
+			want: nil,
+		},
+		{
+			name: "cursor after text without a prompt",
+			screen: fixture{cols: 20,
+				lines:  []string{"Loading files"},
+				cursor: []int{0, 13},
+			},
+			want: nil,
+		},
+		{
+			name: "input in an untitled box",
+			screen: fixture{cols: 20,
+				lines: []string{
+					"┌──────────┐",
+					"│foo       │",
+					"└──────────┘",
+				},
+				cursor: []int{1, 4},
+			},
+			want: []Element{
+				{Kind: KindPanel, Row: 0, Col: 0, Width: 12, Height: 3},
+				{Kind: KindInput, Row: 1, Col: 1, Width: 10, Height: 1, Text: "foo"},
+			},
+		},
+		{
+			name: "hidden cursor",
+			screen: fixture{cols: 20,
+				lines: []string{"Name: bob"},
+			},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Detect(tt.screen.snapshot())
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Detect() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package elements
+
+import "strings"
+
+// promptChars end the label or prompt in front of an input field.
+const promptChars = ":>?$#❯»"
+
+// detectInput reports the field the cursor is in. The field spans from the
+// end of the label or prompt before the cursor to the edge of the enclosing
+// panel (or the screen). For single-line panels the panel title is used as
+// the label. A cursor with neither a prompt before it nor a single-line
+// panel around it is just where output stopped, not a field.
+func detectInput(g *grid) (Element, bool) {
+	snap := g.snap
+	if !snap.CursorVisible || snap.CursorRow < 0 || snap.CursorRow >= g.rows() {
+		return Element{}, false
+	}
+
+	row, col := snap.CursorRow, snap.CursorCol
+	from, to := g.insidePanel(row, col)
+	if col < from || col > to {
+		return Element{}, false
+	}
+
+	start := from
+	label := ""
+	for x := col - 1; x >= from; x-- {
+		if isOneOf(g.char(row, x), promptChars) {
+			label = strings.TrimSpace(g.text(row, from, x+1))
+			start = x + 1
+			if g.char(row, start) == ' ' && start < col {
+				start++
+			}
+			break
+		}
+	}
+
+	inBox := false
+	for _, p := range g.panels {
+		if p.Height == 3 && p.Row == row-1 && p.Col == from-1 {
+			inBox = true
+			if label == "" {
+				label = p.Title
+			}
+		}
+	}
+	if label == "" && !inBox {
+		return Element{}, false
+	}
+
+	return Element{
+		Kind:   KindInput,
+		Row:    row,
+		Col:    start,
+		Width:  to - start,
+		Height: 1,
+		Label:  label,
+		Text:   strings.TrimSpace(g.text(row, start, to)),
+	}, true
+}
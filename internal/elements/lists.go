@@ -0,0 +1,208 @@
+package elements
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/your-username/tui-goggles/internal/terminal"
+)
+
+// Markers that point at the selected row of a list.
+var pointerMarkers = []string{">", "❯", "▶", "▸", "→", "➜", "»"}
+
+// Markers that start an unordered or checkbox list item.
+var bulletMarkers = []string{
+	"•", "-", "*", "◦", "○", "●", "◉", "◯", "▪", "‣",
+	"[ ]", "[x]", "[X]", "[✓]", "( )", "(*)", "(•)",
+}
+
+// listEntry is a candidate list row within one segment of a screen row.
+type listEntry struct {
+	row     int
+	textCol int // column where the item text starts, after any marker
+	text    string
+	// bullet is the family of the entry's bullet marker, or "" if it has
+	// none.
+	bullet   string
+	selected bool
+}
+
+// detectLists groups consecutive rows whose text is aligned on the same
+// column into lists. A group counts as a list when it has a highlighted or
+// pointer-marked row, or when at least two rows carry a bullet marker.
+func detectLists(g *grid) []Element {
+	var lists []Element
+
+	// Open groups keyed by the column their text is aligned on.
+	open := make(map[int][]listEntry)
+	flush := func(textCol int) {
+		if list, ok := buildList(open[textCol]); ok {
+			lists = append(lists, list)
+		}
+		delete(open, textCol)
+	}
+
+	for y := 0; y <= g.rows(); y++ {
+		var entries []listEntry
+		if y < g.rows() && !g.claimed[y] && !g.isBorderRow(y) {
+			entries = g.rowEntries(y)
+		}
+
+		seen := make(map[int]bool)
+		for _, e := range entries {
+			open[e.textCol] = append(open[e.textCol], e)
+			seen[e.textCol] = true
+		}
+		for _, textCol := range sortedKeys(open) {
+			if !seen[textCol] {
+				flush(textCol)
+			}
+		}
+	}
+
+	sort.Slice(lists, func(i, j int) bool {
+		if lists[i].Row != lists[j].Row {
+			return lists[i].Row < lists[j].Row
+		}
+		return lists[i].Col < lists[j].Col
+	})
+	return lists
+}
+
+func sortedKeys(m map[int][]listEntry) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+func buildList(entries []listEntry) (Element, bool) {
+	if len(entries) < 2 {
+		return Element{}, false
+	}
+
+	selected, bullets := 0, 0
+	families := make(map[string]bool)
+	for _, e := range entries {
+		if e.selected {
+			selected++
+		}
+		if e.bullet != "" {
+			bullets++
+			families[e.bullet] = true
+		}
+	}
+	// A list sticks to one kind of marker; lines that merely start with
+	// different ones (prose such as "[x] marks the spot") are not a list.
+	if len(families) > 1 {
+		bullets = 0
+	}
+	if selected == 0 && bullets < 2 {
+		return Element{}, false
+	}
+	// Every row highlighted is more likely a styled block than a selection.
+	if selected == len(entries) && bullets == 0 {
+		return Element{}, false
+	}
+
+	items := make([]Item, len(entries))
+	width := 0
+	for i, e := range entries {
+		items[i] = Item{Text: e.text, Row: e.row, Col: e.textCol, Selected: e.selected}
+		if w := len([]rune(e.text)); w > width {
+			width = w
+		}
+	}
+
+	return Element{
+		Kind:   KindList,
+		Row:    entries[0].row,
+		Col:    entries[0].textCol,
+		Width:  width,
+		Height: len(entries),
+		Items:  items,
+	}, true
+}
+
+// rowEntries splits a row at panel borders and returns one entry for each
+// segment that has text.
+func (g *grid) rowEntries(row int) []listEntry {
+	var entries []listEntry
+
+	start := 0
+	for x := 0; x <= g.cols(); x++ {
+		if x < g.cols() && !isOneOf(g.char(row, x), verticalEdges) {
+			continue
+		}
+		if e, ok := g.segmentEntry(row, start, x); ok {
+			entries = append(entries, e)
+		}
+		start = x + 1
+	}
+
+	return entries
+}
+
+// segmentEntry parses the cells [from, to) of a row as a list entry.
+func (g *grid) segmentEntry(row, from, to int) (listEntry, bool) {
+	raw := g.text(row, from, to)
+	trimmed := strings.TrimLeft(raw, " ")
+	if strings.TrimSpace(trimmed) == "" {
+		return listEntry{}, false
+	}
+
+	col := from + len([]rune(raw)) - len([]rune(trimmed))
+	entry := listEntry{row: row, textCol: col}
+
+	if m, ok := matchMarker(trimmed, pointerMarkers); ok {
+		entry.selected = true
+		entry.textCol = col + len([]rune(m))
+		trimmed = trimmed[len(m):]
+	} else if m, ok := matchMarker(trimmed, bulletMarkers); ok {
+		entry.bullet = bulletFamily(m)
+		entry.textCol = col + len([]rune(m))
+		trimmed = trimmed[len(m):]
+	}
+
+	// Skip the space after the marker so marked and unmarked rows align.
+	for strings.HasPrefix(trimmed, " ") {
+		trimmed = trimmed[1:]
+		entry.textCol++
+	}
+	entry.text = strings.TrimSpace(trimmed)
+
+	for x := from; x < to; x++ {
+		c := g.cell(row, x)
+		if c.Char != ' ' && c.Has(terminal.AttrReverse) {
+			entry.selected = true
+			break
+		}
+	}
+
+	return entry, true
+}
+
+// bulletFamily groups the markers that appear together in one list:
+// checked and unchecked boxes, or set and unset radio buttons.
+func bulletFamily(marker string) string {
+	switch marker {
+	case "[ ]", "[x]", "[X]", "[✓]":
+		return "[ ]"
+	case "( )", "(*)", "(•)", "○", "●", "◉", "◯":
+		return "( )"
+	}
+	return marker
+}
+
+// matchMarker returns the marker the text starts with, if it is followed by
+// a space.
+func matchMarker(text string, markers []string) (string, bool) {
+	for _, m := range markers {
+		if strings.HasPrefix(text, m+" ") {
+			return m, true
+		}
+	}
+	return "", false
+}
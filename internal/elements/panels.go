@@ -0,0 +1,127 @@
+package elements
+
+import "strings"
+
+var (
+	topLeftCorners     = "┌╭╔┏╒╓"
+	topRightCorners    = "┐╮╗┓╕╖"
+	bottomLeftCorners  = "└╰╚┗╘╙"
+	bottomRightCorners = "┘╯╝┛╛╜"
+	horizontalEdges    = "─═━┄┅┈┉╌╍┬┴┼╤╦╧╩┳┻╥╨"
+	verticalEdges      = "│║┃┆┇┊┋╎╏├┤┼╠╣╟╢╞╡┣┫"
+)
+
+func isOneOf(r rune, set string) bool {
+	return strings.ContainsRune(set, r)
+}
+
+// detectPanels finds rectangles drawn with box-drawing characters. Text
+// embedded in the top border is reported as the panel title.
+func detectPanels(g *grid) []Element {
+	var panels []Element
+
+	for y := 0; y < g.rows(); y++ {
+		for x := 0; x < g.cols(); x++ {
+			if !isOneOf(g.char(y, x), topLeftCorners) {
+				continue
+			}
+			if panel, ok := traceBox(g, y, x); ok {
+				panels = append(panels, panel)
+			}
+		}
+	}
+
+	return panels
+}
+
+// traceBox follows a border clockwise from its top-left corner.
+func traceBox(g *grid, top, left int) (Element, bool) {
+	// Top edge: anything except vertical edges and other corners may appear
+	// (titles are drawn inline), up to the first top-right corner.
+	right := -1
+	for x := left + 1; x < g.cols(); x++ {
+		r := g.char(top, x)
+		if isOneOf(r, topRightCorners) {
+			right = x
+			break
+		}
+		if isOneOf(r, topLeftCorners+bottomLeftCorners+bottomRightCorners) {
+			return Element{}, false
+		}
+	}
+	if right < left+1 {
+		return Element{}, false
+	}
+
+	// Left edge down to the bottom-left corner.
+	bottom := -1
+	for y := top + 1; y < g.rows(); y++ {
+		r := g.char(y, left)
+		if isOneOf(r, bottomLeftCorners) {
+			bottom = y
+			break
+		}
+		if !isOneOf(r, verticalEdges) {
+			return Element{}, false
+		}
+	}
+	if bottom < 0 || !isOneOf(g.char(bottom, right), bottomRightCorners) {
+		return Element{}, false
+	}
+
+	for y := top + 1; y < bottom; y++ {
+		if !isOneOf(g.char(y, right), verticalEdges) {
+			return Element{}, false
+		}
+	}
+	// Bottom borders may carry a label too, so only require the edge itself
+	// next to both corners.
+	if right-left > 1 {
+		if !isOneOf(g.char(bottom, left+1), horizontalEdges) || !isOneOf(g.char(bottom, right-1), horizontalEdges) {
+			return Element{}, false
+		}
+	}
+
+	return Element{
+		Kind:   KindPanel,
+		Row:    top,
+		Col:    left,
+		Width:  right - left + 1,
+		Height: bottom - top + 1,
+		Title:  borderTitle(g.text(top, left+1, right)),
+	}, true
+}
+
+// borderTitle extracts the label embedded in a border segment.
+func borderTitle(edge string) string {
+	title := strings.Map(func(r rune) rune {
+		if isOneOf(r, horizontalEdges) {
+			return ' '
+		}
+		return r
+	}, edge)
+	title = strings.TrimSpace(title)
+	title = strings.TrimPrefix(title, "[")
+	title = strings.TrimSuffix(title, "]")
+	return strings.TrimSpace(title)
+}
+
+// insidePanel returns the inner column bounds of the innermost panel that
+// contains the cell, or the full row if there is none.
+func (g *grid) insidePanel(row, col int) (from, to int) {
+	from, to = 0, g.cols()
+	best := -1
+	for _, p := range g.panels {
+		if row <= p.Row || row >= p.Row+p.Height-1 {
+			continue
+		}
+		if col <= p.Col || col >= p.Col+p.Width-1 {
+			continue
+		}
+		if best < 0 || p.Width < best {
+			best = p.Width
+			from, to = p.Col+1, p.Col+p.Width-1
+		}
+	}
+	return from, to
+}
@@ -0,0 +1,88 @@
+package render
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/your-username/tui-goggles/internal/terminal"
+)
+
+// ANSI renders the snapshot as escape sequences that redraw it in a real
+// terminal, starting at the top-left corner, and leave the cursor where the
+// application's cursor is. Each row also clears the rest of its line, so
+// the output can be written over the previous frame.
+func ANSI(snap terminal.Snapshot) string {
+	var sb strings.Builder
+	// Hide the cursor while drawing so it does not flicker across the screen
+	sb.WriteString("\x1b[?25l")
+	for y, row := range snap.Cells {
+		fmt.Fprintf(&sb, "\x1b[%d;1H", y+1)
+		prev := ""
+		for _, cell := range row {
+			if sgr := cellSGR(cell); sgr != prev {
+				sb.WriteString(sgr)
+				prev = sgr
+			}
+			ch := cell.Char
+			if ch == 0 {
+				ch = ' '
+			}
+			sb.WriteRune(ch)
+		}
+		sb.WriteString("\x1b[0m\x1b[K")
+	}
+	fmt.Fprintf(&sb, "\x1b[%d;%dH", snap.CursorRow+1, snap.CursorCol+1)
+	if snap.CursorVisible {
+		sb.WriteString("\x1b[?25h")
+	}
+	return sb.String()
+}
+
+// cellSGR returns the Select Graphic Rendition sequence for a cell's style.
+func cellSGR(c terminal.Cell) string {
+	params := []string{"0"}
+	fg, bg := c.FG, c.BG
+	if c.Has(terminal.AttrReverse) {
+		// The cell's colors are already swapped; let the terminal swap
+		// them instead, so default colors stay the terminal's own
+		fg, bg = bg, fg
+		params = append(params, "7")
+	}
+	if c.Has(terminal.AttrBold) {
+		params = append(params, "1")
+	}
+	if c.Has(terminal.AttrItalic) {
+		params = append(params, "3")
+	}
+	if c.Has(terminal.AttrUnderline) {
+		params = append(params, "4")
+	}
+	if c.Has(terminal.AttrBlink) {
+		params = append(params, "5")
+	}
+	if p := colorSGR(fg, 30); p != "" {
+		params = append(params, p)
+	}
+	if p := colorSGR(bg, 40); p != "" {
+		params = append(params, p)
+	}
+	return "\x1b[" + strings.Join(params, ";") + "m"
+}
+
+// colorSGR returns the SGR parameters selecting a color, base being 30 for
+// the foreground and 40 for the background, or "" for the default color.
+func colorSGR(c terminal.Color, base int) string {
+	switch {
+	case c < 8:
+		return strconv.Itoa(base + int(c))
+	case c < 16:
+		return strconv.Itoa(base + 60 + int(c) - 8)
+	case c < 256:
+		return fmt.Sprintf("%d;5;%d", base+8, c)
+	case c < 1<<24:
+		return fmt.Sprintf("%d;2;%d;%d;%d", base+8, uint8(c>>16), uint8(c>>8), uint8(c))
+	default:
+		return ""
+	}
+}
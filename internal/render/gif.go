@@ -0,0 +1,82 @@
+package render
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/your-username/tui-goggles/internal/terminal"
+)
+
+// GIF writes the snapshots as a looping animated GIF, showing each frame for
+// frameDuration. All frames share one palette so colors do not shift between
+// frames.
+func GIF(w io.Writer, snaps []terminal.Snapshot, frameDuration time.Duration) error {
+	if len(snaps) == 0 {
+		return errors.New("no frames to render")
+	}
+
+	frames := make([]*image.RGBA, 0, len(snaps))
+	for _, snap := range snaps {
+		img, err := Image(snap)
+		if err != nil {
+			return err
+		}
+		frames = append(frames, img)
+	}
+
+	pal := framePalette(frames)
+	// GIF delays are in hundredths of a second.
+	delay := int(frameDuration / (10 * time.Millisecond))
+	if delay < 1 {
+		delay = 1
+	}
+
+	anim := &gif.GIF{}
+	for _, img := range frames {
+		paletted := image.NewPaletted(img.Bounds(), pal)
+		draw.Draw(paletted, img.Bounds(), img, image.Point{}, draw.Src)
+		anim.Image = append(anim.Image, paletted)
+		anim.Delay = append(anim.Delay, delay)
+	}
+	return gif.EncodeAll(w, anim)
+}
+
+// framePalette picks the (at most 256) most frequent colors across all
+// frames. Terminal screens use few colors; the remainder are antialiasing
+// shades that map well to their nearest neighbor.
+func framePalette(frames []*image.RGBA) color.Palette {
+	counts := make(map[color.RGBA]int)
+	for _, img := range frames {
+		for i := 0; i+3 < len(img.Pix); i += 4 {
+			counts[color.RGBA{img.Pix[i], img.Pix[i+1], img.Pix[i+2], 0xff}]++
+		}
+	}
+
+	colors := make([]color.RGBA, 0, len(counts))
+	for c := range counts {
+		colors = append(colors, c)
+	}
+	sort.Slice(colors, func(i, j int) bool {
+		if counts[colors[i]] != counts[colors[j]] {
+			return counts[colors[i]] > counts[colors[j]]
+		}
+		// Break ties deterministically so output is reproducible.
+		a, b := colors[i], colors[j]
+		return uint32(a.R)<<16|uint32(a.G)<<8|uint32(a.B) < uint32(b.R)<<16|uint32(b.G)<<8|uint32(b.B)
+	})
+	if len(colors) > 256 {
+		colors = colors[:256]
+	}
+
+	pal := make(color.Palette, len(colors))
+	for i, c := range colors {
+		pal[i] = c
+	}
+	return pal
+}
@@ -0,0 +1,45 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/your-username/tui-goggles/internal/terminal"
+)
+
+// HTML renders the snapshot as a <pre> block of inline-styled spans. The
+// markup is self-contained so it can be embedded in any page.
+func HTML(snap terminal.Snapshot) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<pre class="screen" style="background:%s;color:%s">`,
+		hex(DefaultBackground), hex(DefaultForeground))
+
+	for y := range snap.Cells {
+		for _, r := range rowRuns(snap, y) {
+			var style []string
+			if r.fg != DefaultForeground {
+				style = append(style, "color:"+hex(r.fg))
+			}
+			if r.bg != DefaultBackground {
+				style = append(style, "background:"+hex(r.bg))
+			}
+			if r.bold {
+				style = append(style, "font-weight:bold")
+			}
+			if r.underline {
+				style = append(style, "text-decoration:underline")
+			}
+
+			text := escapeXML(string(r.text))
+			if len(style) == 0 {
+				sb.WriteString(text)
+				continue
+			}
+			fmt.Fprintf(&sb, `<span style="%s">%s</span>`, strings.Join(style, ";"), text)
+		}
+		sb.WriteByte('\n')
+	}
+
+	sb.WriteString("</pre>")
+	return sb.String()
+}
@@ -0,0 +1,323 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/gomonobold"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+
+	"github.com/your-username/tui-goggles/internal/terminal"
+)
+
+// fontSize is the point size used for raster output (at 72 DPI, so points
+// are pixels).
+const fontSize = 14
+
+// fonts holds the parsed faces and the cell metrics derived from them.
+type fonts struct {
+	regular font.Face
+	bold    font.Face
+	cellW   int
+	cellH   int
+	ascent  int
+}
+
+var (
+	loadFontsOnce sync.Once
+	loadedFonts   *fonts
+	loadFontsErr  error
+)
+
+func loadFonts() (*fonts, error) {
+	loadFontsOnce.Do(func() {
+		regular, err := newFace(gomono.TTF)
+		if err != nil {
+			loadFontsErr = err
+			return
+		}
+		bold, err := newFace(gomonobold.TTF)
+		if err != nil {
+			loadFontsErr = err
+			return
+		}
+
+		metrics := regular.Metrics()
+		advance, _ := regular.GlyphAdvance('M')
+		loadedFonts = &fonts{
+			regular: regular,
+			bold:    bold,
+			cellW:   advance.Ceil(),
+			cellH:   (metrics.Ascent + metrics.Descent).Ceil(),
+			ascent:  metrics.Ascent.Ceil(),
+		}
+	})
+	return loadedFonts, loadFontsErr
+}
+
+func newFace(ttf []byte) (font.Face, error) {
+	f, err := opentype.Parse(ttf)
+	if err != nil {
+		return nil, err
+	}
+	return opentype.NewFace(f, &opentype.FaceOptions{
+		Size:    fontSize,
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+}
+
+// Image rasterizes the snapshot: one font cell per terminal cell, with
+// colors, bold, underline and a block cursor.
+func Image(snap terminal.Snapshot) (*image.RGBA, error) {
+	f, err := loadFonts()
+	if err != nil {
+		return nil, err
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, snap.Cols*f.cellW, snap.Rows*f.cellH))
+	draw.Draw(img, img.Bounds(), image.NewUniform(DefaultBackground), image.Point{}, draw.Src)
+
+	for y, row := range snap.Cells {
+		for x, cell := range row {
+			fg, bg := cellColors(snap, y, x)
+			rect := image.Rect(x*f.cellW, y*f.cellH, (x+1)*f.cellW, (y+1)*f.cellH)
+			draw.Draw(img, rect, image.NewUniform(bg), image.Point{}, draw.Src)
+
+			if cell.Char != ' ' && cell.Char != 0 && !drawBoxChar(img, rect, cell.Char, fg) {
+				face := f.regular
+				if cell.Has(terminal.AttrBold) {
+					face = f.bold
+				}
+				drawGlyph(img, rect, face, f.ascent, cell.Char, fg)
+			}
+
+			if cell.Has(terminal.AttrUnderline) {
+				line := image.Rect(rect.Min.X, rect.Min.Y+f.ascent+1, rect.Max.X, rect.Min.Y+f.ascent+2)
+				draw.Draw(img, line, image.NewUniform(fg), image.Point{}, draw.Src)
+			}
+		}
+	}
+
+	return img, nil
+}
+
+// PNG writes the snapshot as a PNG image.
+func PNG(w io.Writer, snap terminal.Snapshot) error {
+	img, err := Image(snap)
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, img)
+}
+
+// Box-drawing line segments, as a bit set of directions from the center.
+const (
+	lineUp = 1 << iota
+	lineDown
+	lineLeft
+	lineRight
+)
+
+// Line weights of box-drawing characters.
+const (
+	lineLight = iota
+	lineHeavy
+	lineDouble
+)
+
+// boxChar is the segments a box-drawing character draws and their weight.
+type boxChar struct {
+	lines  int
+	weight int
+}
+
+// boxChars maps box-drawing characters to the segments they draw. These are
+// drawn as lines rather than font glyphs so adjacent cells join up
+// seamlessly regardless of font metrics, and because the font lacks the
+// heavy ones. Dashed lines are drawn solid, and characters mixing weights
+// in the heavier one.
+var boxChars = map[rune]boxChar{
+	'─': {lineLeft | lineRight, lineLight}, '━': {lineLeft | lineRight, lineHeavy}, '═': {lineLeft | lineRight, lineDouble},
+	'│': {lineUp | lineDown, lineLight}, '┃': {lineUp | lineDown, lineHeavy}, '║': {lineUp | lineDown, lineDouble},
+	'┄': {lineLeft | lineRight, lineLight}, '┅': {lineLeft | lineRight, lineHeavy},
+	'┈': {lineLeft | lineRight, lineLight}, '┉': {lineLeft | lineRight, lineHeavy},
+	'╌': {lineLeft | lineRight, lineLight}, '╍': {lineLeft | lineRight, lineHeavy},
+	'┆': {lineUp | lineDown, lineLight}, '┇': {lineUp | lineDown, lineHeavy},
+	'┊': {lineUp | lineDown, lineLight}, '┋': {lineUp | lineDown, lineHeavy},
+	'╎': {lineUp | lineDown, lineLight}, '╏': {lineUp | lineDown, lineHeavy},
+
+	'┌': {lineDown | lineRight, lineLight}, '╭': {lineDown | lineRight, lineLight},
+	'┏': {lineDown | lineRight, lineHeavy}, '╔': {lineDown | lineRight, lineDouble},
+	'╒': {lineDown | lineRight, lineDouble}, '╓': {lineDown | lineRight, lineDouble},
+	'┐': {lineDown | lineLeft, lineLight}, '╮': {lineDown | lineLeft, lineLight},
+	'┓': {lineDown | lineLeft, lineHeavy}, '╗': {lineDown | lineLeft, lineDouble},
+	'╕': {lineDown | lineLeft, lineDouble}, '╖': {lineDown | lineLeft, lineDouble},
+	'└': {lineUp | lineRight, lineLight}, '╰': {lineUp | lineRight, lineLight},
+	'┗': {lineUp | lineRight, lineHeavy}, '╚': {lineUp | lineRight, lineDouble},
+	'╘': {lineUp | lineRight, lineDouble}, '╙': {lineUp | lineRight, lineDouble},
+	'┘': {lineUp | lineLeft, lineLight}, '╯': {lineUp | lineLeft, lineLight},
+	'┛': {lineUp | lineLeft, lineHeavy}, '╝': {lineUp | lineLeft, lineDouble},
+	'╛': {lineUp | lineLeft, lineDouble}, '╜': {lineUp | lineLeft, lineDouble},
+
+	'├': {lineUp | lineDown | lineRight, lineLight}, '┣': {lineUp | lineDown | lineRight, lineHeavy},
+	'╠': {lineUp | lineDown | lineRight, lineDouble}, '╞': {lineUp | lineDown | lineRight, lineDouble},
+	'╟': {lineUp | lineDown | lineRight, lineDouble},
+	'┤': {lineUp | lineDown | lineLeft, lineLight}, '┫': {lineUp | lineDown | lineLeft, lineHeavy},
+	'╣': {lineUp | lineDown | lineLeft, lineDouble}, '╡': {lineUp | lineDown | lineLeft, lineDouble},
+	'╢': {lineUp | lineDown | lineLeft, lineDouble},
+	'┬': {lineDown | lineLeft | lineRight, lineLight}, '┳': {lineDown | lineLeft | lineRight, lineHeavy},
+	'╦': {lineDown | lineLeft | lineRight, lineDouble}, '╤': {lineDown | lineLeft | lineRight, lineDouble},
+	'╥': {lineDown | lineLeft | lineRight, lineDouble},
+	'┴': {lineUp | lineLeft | lineRight, lineLight}, '┻': {lineUp | lineLeft | lineRight, lineHeavy},
+	'╩': {lineUp | lineLeft | lineRight, lineDouble}, '╧': {lineUp | lineLeft | lineRight, lineDouble},
+	'╨': {lineUp | lineLeft | lineRight, lineDouble},
+	'┼': {lineUp | lineDown | lineLeft | lineRight, lineLight}, '╋': {lineUp | lineDown | lineLeft | lineRight, lineHeavy},
+	'╬': {lineUp | lineDown | lineLeft | lineRight, lineDouble},
+}
+
+// lineOffsets are the offsets from the center at which each weight draws a
+// one pixel line.
+var lineOffsets = map[int][]int{
+	lineLight:  {0},
+	lineHeavy:  {0, 1},
+	lineDouble: {-1, 1},
+}
+
+// Block elements, by how many eighths of the cell they fill from the bottom
+// (lowerBlocks) or from the left (leftBlocks).
+var (
+	lowerBlocks = map[rune]int{'▁': 1, '▂': 2, '▃': 3, '▄': 4, '▅': 5, '▆': 6, '▇': 7, '█': 8}
+	leftBlocks  = map[rune]int{'▏': 1, '▎': 2, '▍': 3, '▌': 4, '▋': 5, '▊': 6, '▉': 7}
+)
+
+// glyphFallbacks are look-alikes, present in the font, for symbols TUIs
+// commonly use that it lacks.
+var glyphFallbacks = map[rune]rune{
+	'✓': '√', '✔': '√',
+	'✗': '×', '✘': '×', '✕': '×', '✖': '×',
+	'❯': '›', '❮': '‹',
+	'▶': '►', '▸': '►', '◀': '◄', '◂': '◄',
+	'◉': '●', '◯': '○',
+	'⚠': '!', 'ℹ': 'i',
+}
+
+// drawGlyph draws a character from the font face, or a look-alike from
+// glyphFallbacks if the font lacks it. Characters with neither are drawn as
+// a hollow box.
+func drawGlyph(img *image.RGBA, rect image.Rectangle, face font.Face, ascent int, r rune, fg color.RGBA) {
+	if _, ok := face.GlyphAdvance(r); !ok {
+		sub, ok := glyphFallbacks[r]
+		if !ok {
+			drawMissing(img, rect, fg)
+			return
+		}
+		r = sub
+	}
+	d := font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(fg),
+		Face: face,
+		Dot:  fixed.P(rect.Min.X, rect.Min.Y+ascent),
+	}
+	d.DrawString(string(r))
+}
+
+// drawMissing marks a character the font cannot show with a hollow box.
+func drawMissing(img *image.RGBA, rect image.Rectangle, fg color.RGBA) {
+	box := rect.Inset(2)
+	if box.Empty() {
+		return
+	}
+	src := image.NewUniform(fg)
+	for _, edge := range []image.Rectangle{
+		image.Rect(box.Min.X, box.Min.Y, box.Max.X, box.Min.Y+1),
+		image.Rect(box.Min.X, box.Max.Y-1, box.Max.X, box.Max.Y),
+		image.Rect(box.Min.X, box.Min.Y, box.Min.X+1, box.Max.Y),
+		image.Rect(box.Max.X-1, box.Min.Y, box.Max.X, box.Max.Y),
+	} {
+		draw.Draw(img, edge, src, image.Point{}, draw.Src)
+	}
+}
+
+// drawBoxChar draws box-drawing, block and Braille characters
+// procedurally. It returns false for characters it does not handle.
+func drawBoxChar(img *image.RGBA, rect image.Rectangle, r rune, fg color.RGBA) bool {
+	src := image.NewUniform(fg)
+	fill := func(rect image.Rectangle) {
+		draw.Draw(img, rect, src, image.Point{}, draw.Src)
+	}
+	w, h := rect.Dx(), rect.Dy()
+	midX := (rect.Min.X + rect.Max.X) / 2
+	midY := (rect.Min.Y + rect.Max.Y) / 2
+
+	if n, ok := lowerBlocks[r]; ok {
+		fill(image.Rect(rect.Min.X, rect.Max.Y-h*n/8, rect.Max.X, rect.Max.Y))
+		return true
+	}
+	if n, ok := leftBlocks[r]; ok {
+		fill(image.Rect(rect.Min.X, rect.Min.Y, rect.Min.X+w*n/8, rect.Max.Y))
+		return true
+	}
+	switch r {
+	case '▀':
+		fill(image.Rect(rect.Min.X, rect.Min.Y, rect.Max.X, midY))
+		return true
+	case '▔':
+		fill(image.Rect(rect.Min.X, rect.Min.Y, rect.Max.X, rect.Min.Y+h/8))
+		return true
+	case '▐':
+		fill(image.Rect(midX, rect.Min.Y, rect.Max.X, rect.Max.Y))
+		return true
+	case '▕':
+		fill(image.Rect(rect.Max.X-w/8, rect.Min.Y, rect.Max.X, rect.Max.Y))
+		return true
+	}
+
+	// Braille patterns: dots 1-3 and 7 run down the left column, 4-6 and 8
+	// down the right, one bit each from U+2800
+	if r >= 0x2800 && r <= 0x28FF {
+		bits := int(r - 0x2800)
+		dots := [8][2]int{{0, 0}, {0, 1}, {0, 2}, {1, 0}, {1, 1}, {1, 2}, {0, 3}, {1, 3}}
+		size := w / 4
+		if size < 1 {
+			size = 1
+		}
+		for i, dot := range dots {
+			if bits&(1<<i) == 0 {
+				continue
+			}
+			x := rect.Min.X + w*(2*dot[0]+1)/4 - size/2
+			y := rect.Min.Y + h*(2*dot[1]+1)/8 - size/2
+			fill(image.Rect(x, y, x+size, y+size))
+		}
+		return true
+	}
+
+	box, ok := boxChars[r]
+	if !ok {
+		return false
+	}
+	for _, o := range lineOffsets[box.weight] {
+		if box.lines&lineUp != 0 {
+			fill(image.Rect(midX+o, rect.Min.Y, midX+o+1, midY+1))
+		}
+		if box.lines&lineDown != 0 {
+			fill(image.Rect(midX+o, midY, midX+o+1, rect.Max.Y))
+		}
+		if box.lines&lineLeft != 0 {
+			fill(image.Rect(rect.Min.X, midY+o, midX+1, midY+o+1))
+		}
+		if box.lines&lineRight != 0 {
+			fill(image.Rect(midX, midY+o, rect.Max.X, midY+o+1))
+		}
+	}
+	return true
+}
@@ -0,0 +1,107 @@
+package render
+
+import (
+	"bytes"
+	"image"
+	"testing"
+
+	"github.com/your-username/tui-goggles/internal/terminal"
+)
+
+// renderCell rasterizes a one-cell screen holding r.
+func renderCell(t *testing.T, r rune) *image.RGBA {
+	t.Helper()
+	snap := terminal.Snapshot{Cols: 1, Rows: 1, Cells: [][]terminal.Cell{{
+		{Char: r, FG: terminal.DefaultFG, BG: terminal.DefaultBG},
+	}}}
+	img, err := Image(snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+// inked reports whether the pixel at x, y differs from the background.
+func inked(img *image.RGBA, x, y int) bool {
+	return img.RGBAAt(x, y) != DefaultBackground
+}
+
+func TestImageGlyphFallbacks(t *testing.T) {
+	tests := []struct {
+		r, want rune
+	}{
+		{'✓', '√'},
+		{'✔', '√'},
+		{'✗', '×'},
+		{'✘', '×'},
+		{'❯', '›'},
+		{'▶', '►'},
+		{'◉', '●'},
+	}
+	for _, tt := range tests {
+		got, want := renderCell(t, tt.r), renderCell(t, tt.want)
+		if !bytes.Equal(got.Pix, want.Pix) {
+			t.Errorf("%q is not drawn as %q", tt.r, tt.want)
+		}
+		if bytes.Equal(got.Pix, renderCell(t, ' ').Pix) {
+			t.Errorf("%q is drawn blank", tt.r)
+		}
+	}
+}
+
+func TestImageMissingGlyph(t *testing.T) {
+	img := renderCell(t, '🙂')
+	b := img.Bounds().Inset(2)
+	if !inked(img, b.Min.X, b.Min.Y) || !inked(img, b.Max.X-1, b.Max.Y-1) {
+		t.Error("missing glyph is not outlined with a box")
+	}
+	mid := image.Pt((b.Min.X+b.Max.X)/2, (b.Min.Y+b.Max.Y)/2)
+	if inked(img, mid.X, mid.Y) {
+		t.Error("missing glyph box is not hollow")
+	}
+}
+
+func TestImageDrawnShapes(t *testing.T) {
+	tests := []struct {
+		r     rune
+		inked [][2]float64 // points, as fractions of the cell, that are drawn
+		blank [][2]float64 // points that are left as background
+	}{
+		{'━', [][2]float64{{0, 0.5}, {0.99, 0.5}}, [][2]float64{{0.5, 0}, {0.5, 0.99}}},
+		{'┃', [][2]float64{{0.5, 0}, {0.5, 0.99}}, [][2]float64{{0, 0.5}, {0.99, 0.5}}},
+		{'┏', [][2]float64{{0.5, 0.99}, {0.99, 0.5}}, [][2]float64{{0.5, 0}, {0, 0.5}}},
+		{'╋', [][2]float64{{0.5, 0}, {0.5, 0.99}, {0, 0.5}, {0.99, 0.5}}, nil},
+		{'▁', [][2]float64{{0.5, 0.99}}, [][2]float64{{0.5, 0.5}}},
+		{'▇', [][2]float64{{0.5, 0.3}}, [][2]float64{{0.5, 0}}},
+		{'▔', [][2]float64{{0.5, 0}}, [][2]float64{{0.5, 0.5}}},
+		{'▎', [][2]float64{{0, 0.5}}, [][2]float64{{0.5, 0.5}}},
+		{'⣿', [][2]float64{{0.25, 1.0 / 8}, {0.75, 7.0 / 8}}, nil},
+		{'⠁', [][2]float64{{0.25, 1.0 / 8}}, [][2]float64{{0.75, 7.0 / 8}}},
+	}
+	for _, tt := range tests {
+		img := renderCell(t, tt.r)
+		w, h := float64(img.Bounds().Dx()), float64(img.Bounds().Dy())
+		at := func(p [2]float64) (int, int) { return int(p[0] * w), int(p[1] * h) }
+		for _, p := range tt.inked {
+			if x, y := at(p); !inked(img, x, y) {
+				t.Errorf("%q: pixel %d,%d is not drawn", tt.r, x, y)
+			}
+		}
+		for _, p := range tt.blank {
+			if x, y := at(p); inked(img, x, y) {
+				t.Errorf("%q: pixel %d,%d is drawn", tt.r, x, y)
+			}
+		}
+	}
+}
+
+func TestImageDoubleLines(t *testing.T) {
+	img := renderCell(t, '║')
+	midX, y := img.Bounds().Dx()/2, img.Bounds().Dy()/2
+	if !inked(img, midX-1, y) || !inked(img, midX+1, y) {
+		t.Error("'║' is not drawn as two lines")
+	}
+	if inked(img, midX, y) {
+		t.Error("'║' has no gap between its lines")
+	}
+}
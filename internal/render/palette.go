@@ -0,0 +1,73 @@
+// Package render draws captured screen grids as images (PNG, SVG, GIF).
+//
+// Rendering is pure Go and uses the embedded Go Mono font, so it runs
+// headless without any external tools.
+package render
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/your-username/tui-goggles/internal/terminal"
+)
+
+// Default colors, matching the black background and white foreground the
+// terminal reports to OSC 10/11 queries.
+var (
+	DefaultForeground = color.RGBA{0xe5, 0xe5, 0xe5, 0xff}
+	DefaultBackground = color.RGBA{0x00, 0x00, 0x00, 0xff}
+)
+
+// ansiColors are the 16 standard colors (xterm defaults).
+var ansiColors = [16]color.RGBA{
+	{0x00, 0x00, 0x00, 0xff}, {0xcd, 0x00, 0x00, 0xff}, {0x00, 0xcd, 0x00, 0xff}, {0xcd, 0xcd, 0x00, 0xff},
+	{0x00, 0x00, 0xee, 0xff}, {0xcd, 0x00, 0xcd, 0xff}, {0x00, 0xcd, 0xcd, 0xff}, {0xe5, 0xe5, 0xe5, 0xff},
+	{0x7f, 0x7f, 0x7f, 0xff}, {0xff, 0x00, 0x00, 0xff}, {0x00, 0xff, 0x00, 0xff}, {0xff, 0xff, 0x00, 0xff},
+	{0x5c, 0x5c, 0xff, 0xff}, {0xff, 0x00, 0xff, 0xff}, {0x00, 0xff, 0xff, 0xff}, {0xff, 0xff, 0xff, 0xff},
+}
+
+// RGB resolves a terminal color to an RGB value. Colors 16-231 are the xterm
+// 6x6x6 cube, 232-255 the grayscale ramp, and larger values 24-bit RGB.
+func RGB(c terminal.Color) color.RGBA {
+	switch {
+	case c == terminal.DefaultFG:
+		return DefaultForeground
+	case c == terminal.DefaultBG:
+		return DefaultBackground
+	case c < 16:
+		return ansiColors[c]
+	case c < 232:
+		i := int(c) - 16
+		return color.RGBA{cubeLevel(i / 36), cubeLevel((i / 6) % 6), cubeLevel(i % 6), 0xff}
+	case c < 256:
+		v := uint8(8 + (int(c)-232)*10)
+		return color.RGBA{v, v, v, 0xff}
+	case c < 1<<24:
+		return color.RGBA{uint8(c >> 16), uint8(c >> 8), uint8(c), 0xff}
+	default:
+		return DefaultForeground
+	}
+}
+
+func cubeLevel(i int) uint8 {
+	if i == 0 {
+		return 0
+	}
+	return uint8(55 + i*40)
+}
+
+// hex formats a color as #rrggbb.
+func hex(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// cellColors returns the colors a cell is drawn with, swapped when the
+// cursor is on it.
+func cellColors(snap terminal.Snapshot, row, col int) (fg, bg color.RGBA) {
+	c := snap.Cells[row][col]
+	fg, bg = RGB(c.FG), RGB(c.BG)
+	if snap.CursorVisible && row == snap.CursorRow && col == snap.CursorCol {
+		fg, bg = bg, fg
+	}
+	return fg, bg
+}
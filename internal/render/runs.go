@@ -0,0 +1,41 @@
+package render
+
+import (
+	"image/color"
+
+	"github.com/your-username/tui-goggles/internal/terminal"
+)
+
+// run is a horizontal run of cells drawn with the same style.
+type run struct {
+	col       int
+	text      []rune
+	fg, bg    color.RGBA
+	bold      bool
+	underline bool
+}
+
+// rowRuns splits a row into runs of identically styled cells.
+func rowRuns(snap terminal.Snapshot, row int) []run {
+	var runs []run
+	for x, cell := range snap.Cells[row] {
+		fg, bg := cellColors(snap, row, x)
+		bold := cell.Has(terminal.AttrBold)
+		underline := cell.Has(terminal.AttrUnderline)
+
+		ch := cell.Char
+		if ch == 0 {
+			ch = ' '
+		}
+
+		if n := len(runs); n > 0 {
+			last := &runs[n-1]
+			if last.fg == fg && last.bg == bg && last.bold == bold && last.underline == underline {
+				last.text = append(last.text, ch)
+				continue
+			}
+		}
+		runs = append(runs, run{col: x, text: []rune{ch}, fg: fg, bg: bg, bold: bold, underline: underline})
+	}
+	return runs
+}
@@ -0,0 +1,98 @@
+package render
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/image/font/gofont/gomono"
+
+	"github.com/your-username/tui-goggles/internal/terminal"
+)
+
+// SVG cell metrics. Go Mono's advance is 0.6em, so text runs line up with
+// the grid; textLength pins each run to its cells regardless.
+const (
+	svgFontSize = 14
+	svgCellW    = 8.4
+	svgCellH    = 17
+	svgBaseline = 13
+)
+
+// SVG renders the snapshot as a standalone SVG document with the Go Mono
+// font embedded.
+func SVG(snap terminal.Snapshot) string {
+	width := float64(snap.Cols) * svgCellW
+	height := snap.Rows * svgCellH
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%.1f" height="%d" viewBox="0 0 %.1f %d">`+"\n",
+		width, height, width, height)
+	sb.WriteString("<style>\n")
+	sb.WriteString("@font-face { font-family: 'Go Mono Embedded'; src: url(data:font/ttf;base64,")
+	sb.WriteString(base64.StdEncoding.EncodeToString(gomono.TTF))
+	sb.WriteString(") format('truetype'); }\n")
+	fmt.Fprintf(&sb, "text { font-family: 'Go Mono Embedded', monospace; font-size: %dpx; white-space: pre; }\n", svgFontSize)
+	sb.WriteString(".b { font-weight: bold; } .u { text-decoration: underline; }\n")
+	sb.WriteString("</style>\n")
+	fmt.Fprintf(&sb, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", hex(DefaultBackground))
+
+	for y := range snap.Cells {
+		runs := rowRuns(snap, y)
+		top := y * svgCellH
+
+		for _, run := range runs {
+			if run.bg == DefaultBackground {
+				continue
+			}
+			fmt.Fprintf(&sb, `<rect x="%.1f" y="%d" width="%.1f" height="%d" fill="%s"/>`+"\n",
+				float64(run.col)*svgCellW, top, float64(len(run.text))*svgCellW, svgCellH, hex(run.bg))
+		}
+
+		for _, run := range runs {
+			if strings.TrimSpace(string(run.text)) == "" && !run.underline {
+				continue
+			}
+			var classes []string
+			if run.bold {
+				classes = append(classes, "b")
+			}
+			if run.underline {
+				classes = append(classes, "u")
+			}
+			class := ""
+			if len(classes) > 0 {
+				class = fmt.Sprintf(` class="%s"`, strings.Join(classes, " "))
+			}
+			fmt.Fprintf(&sb, `<text x="%.1f" y="%d" textLength="%.1f" lengthAdjust="spacingAndGlyphs" fill="%s"%s>%s</text>`+"\n",
+				float64(run.col)*svgCellW, top+svgBaseline, float64(len(run.text))*svgCellW,
+				hex(run.fg), class, escapeXML(string(run.text)))
+		}
+	}
+
+	sb.WriteString("</svg>\n")
+	return sb.String()
+}
+
+func escapeXML(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		switch r {
+		case '&':
+			sb.WriteString("&amp;")
+		case '<':
+			sb.WriteString("&lt;")
+		case '>':
+			sb.WriteString("&gt;")
+		case '"':
+			sb.WriteString("&quot;")
+		default:
+			if r < 0x20 {
+				// Control characters are not allowed in XML.
+				r = ' '
+			}
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,324 @@
+package terminal
+
+import (
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// outputTailSize is how much of the latest output is kept for Expect.
+const outputTailSize = 64 << 10
+
+// Reasons an Expect run ends.
+const (
+	// ExpectUntil means the Until pattern matched.
+	ExpectUntil = "until"
+	// ExpectIdle means the screen stayed unchanged for Idle with no rule
+	// matching (and no Until pattern was given).
+	ExpectIdle = "idle"
+	// ExpectExited means the command's output ended.
+	ExpectExited = "exited"
+	// ExpectMaxIterations means rules fired MaxIterations times.
+	ExpectMaxIterations = "max_iterations"
+	// ExpectTimeout means Timeout elapsed first.
+	ExpectTimeout = "timeout"
+)
+
+// ExpectRule sends input when the screen matches a pattern.
+type ExpectRule struct {
+	Pattern *regexp.Regexp
+	// LastLine matches only the last non-blank line instead of the whole
+	// screen.
+	LastLine bool
+	// Send is written to the application when the rule fires.
+	Send string
+}
+
+// ExpectOptions controls an Expect run.
+type ExpectOptions struct {
+	// Until ends the run successfully when it matches the screen. Without
+	// it, the run ends once no rule matches and the screen has not changed
+	// for Idle after settling.
+	Until *regexp.Regexp
+	// Idle is how long to wait for more output, when no rule matches, before
+	// ending a run without Until; slow applications need a longer one.
+	Idle time.Duration
+	// MaxIterations bounds how many times rules may fire (0 means 100).
+	MaxIterations int
+	// Quiet is how long the screen must go without updates before it is
+	// matched, so rules see a finished redraw.
+	Quiet time.Duration
+	// Timeout bounds the whole run.
+	Timeout time.Duration
+}
+
+// ExpectFiring records one rule firing.
+type ExpectFiring struct {
+	Rule      int     `json:"rule"`
+	Match     string  `json:"match"`
+	ElapsedMs float64 `json:"elapsed_ms"`
+}
+
+// ExpectResult reports how an Expect run went.
+type ExpectResult struct {
+	Reason     string         `json:"reason"`
+	Iterations int            `json:"iterations"`
+	Fired      []ExpectFiring `json:"fired,omitempty"`
+}
+
+// Expect drives the application with rules: whenever the screen has settled
+// it is matched against the rules in order and the first match sends its
+// input, until a terminal condition is reached. Waiting is driven by screen
+// change notifications rather than fixed delays.
+//
+// A rule fires once per occurrence of its pattern: a prompt that is still
+// on screen after it was answered is not answered again, unless the
+// application writes it anew (as when the next prompt appears in the same
+// place after scrolling).
+func (t *Terminal) Expect(rules []ExpectRule, opts ExpectOptions) ExpectResult {
+	if opts.MaxIterations <= 0 {
+		opts.MaxIterations = 100
+	}
+	start := time.Now()
+	deadline := start.Add(opts.Timeout)
+	result := ExpectResult{}
+	var answered []expectOccurrence
+
+	for {
+		if !t.waitQuiet(opts.Quiet, deadline) {
+			if time.Now().After(deadline) {
+				result.Reason = ExpectTimeout
+			} else {
+				result.Reason = ExpectExited
+			}
+			return result
+		}
+
+		offset := t.outputOffset()
+		screen := trimLines(t.Screenshot())
+		if opts.Until != nil && opts.Until.MatchString(screen) {
+			result.Reason = ExpectUntil
+			return result
+		}
+
+		fired := false
+		for i, rule := range rules {
+			occ, ok := t.newOccurrence(i, rule, screen, answered)
+			if !ok {
+				continue
+			}
+
+			if result.Iterations >= opts.MaxIterations {
+				result.Reason = ExpectMaxIterations
+				return result
+			}
+			if err := t.SendKeys(rule.Send); err != nil {
+				result.Reason = ExpectExited
+				return result
+			}
+			result.Iterations++
+			result.Fired = append(result.Fired, ExpectFiring{
+				Rule:      i,
+				Match:     occ.text,
+				ElapsedMs: millis(time.Since(start)),
+			})
+			answered = append(answered, occ)
+			fired = true
+			break
+		}
+		// Whatever was on screen now has been seen; only text written
+		// from here on can make an answered prompt new again
+		for i := range answered {
+			answered[i].offset = offset
+		}
+		if fired {
+			continue
+		}
+
+		// Nothing to do until the screen changes; without Until, give up
+		// once it has not for Idle
+		changeDeadline := deadline
+		if opts.Until == nil {
+			if idle := time.Now().Add(opts.Idle); idle.Before(deadline) {
+				changeDeadline = idle
+			}
+		}
+		if !t.waitChange(changeDeadline) {
+			switch {
+			case time.Now().After(deadline):
+				result.Reason = ExpectTimeout
+			case opts.Until == nil && !t.outputEnded():
+				result.Reason = ExpectIdle
+			default:
+				result.Reason = ExpectExited
+			}
+			return result
+		}
+	}
+}
+
+// expectOccurrence is a match of a rule that was answered: where it was on
+// screen and how much output had been read when the screen was last
+// matched.
+type expectOccurrence struct {
+	rule     int
+	row, col int
+	text     string
+	offset   int64
+}
+
+// newOccurrence returns the first match of the rule on the screen that has
+// not been answered yet. Matches at a place where the same text was not
+// answered before come first; failing those, a match at an answered place
+// counts if the text has been written again since the screen was last
+// matched (the next prompt in the last row after scrolling).
+func (t *Terminal) newOccurrence(i int, rule ExpectRule, screen string, answered []expectOccurrence) (expectOccurrence, bool) {
+	target, firstRow := screen, 0
+	if rule.LastLine {
+		target, firstRow = lastLine(screen)
+	}
+	var rewritten []expectOccurrence
+	for _, loc := range rule.Pattern.FindAllStringIndex(target, -1) {
+		before := target[:loc[0]]
+		lineStart := strings.LastIndex(before, "\n") + 1
+		occ := expectOccurrence{
+			rule: i,
+			row:  firstRow + strings.Count(before, "\n"),
+			col:  utf8.RuneCountInString(before[lineStart:]),
+			text: target[loc[0]:loc[1]],
+		}
+		prev := -1
+		for j, a := range answered {
+			if a.rule == occ.rule && a.row == occ.row && a.col == occ.col && a.text == occ.text {
+				prev = j
+				break
+			}
+		}
+		if prev < 0 {
+			return occ, true
+		}
+		if t.writtenSince(answered[prev].offset, occ.text) {
+			rewritten = append(rewritten, occ)
+		}
+	}
+	if len(rewritten) > 0 {
+		return rewritten[0], true
+	}
+	return expectOccurrence{}, false
+}
+
+// appendOutput records output read from the command. The caller must hold
+// t.mu.
+func (t *Terminal) appendOutput(data []byte) {
+	t.outputTotal += int64(len(data))
+	t.output = append(t.output, data...)
+	if excess := len(t.output) - outputTailSize; excess > 0 {
+		t.output = append(t.output[:0], t.output[excess:]...)
+	}
+}
+
+// outputOffset returns how much output has been read so far.
+func (t *Terminal) outputOffset() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.outputTotal
+}
+
+// writtenSince reports whether text appears in the output read after
+// offset, ignoring escape sequences. If that output is no longer kept, it
+// is assumed to have been.
+func (t *Terminal) writtenSince(offset int64, text string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	start := int64(len(t.output)) - (t.outputTotal - offset)
+	if start < 0 {
+		return true
+	}
+	return strings.Contains(plainOutput(t.output[start:]), text)
+}
+
+// outputEnded reports whether the command's output has ended.
+func (t *Terminal) outputEnded() bool {
+	select {
+	case <-t.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// escapeSequence matches CSI, OSC and other escape sequences.
+var escapeSequence = regexp.MustCompile(`\x1b(\[[0-?]*[ -/]*[@-~]|\][^\x07\x1b]*(\x07|\x1b\\)|[ -/]*[0-~])`)
+
+// plainOutput strips escape sequences and carriage returns from output,
+// leaving the text that was written.
+func plainOutput(data []byte) string {
+	return strings.ReplaceAll(escapeSequence.ReplaceAllString(string(data), ""), "\r", "")
+}
+
+// waitQuiet waits until the screen has gone without updates for quiet.
+// It returns false at the deadline, or once output has ended (after first
+// returning true when it ends, so the final screen is still matched).
+func (t *Terminal) waitQuiet(quiet time.Duration, deadline time.Time) bool {
+	select {
+	case <-t.done:
+		return false
+	default:
+	}
+
+	done := t.trace.wait(WaitStable, "")
+	timer := time.NewTimer(quiet)
+	defer timer.Stop()
+	for {
+		changed := t.Changed()
+		select {
+		case <-timer.C:
+			done(ReasonStable)
+			return true
+		case <-changed:
+			if !timer.Stop() {
+				<-timer.C
+			}
+			timer.Reset(quiet)
+		case <-t.done:
+			done(ReasonExited)
+			return true
+		case <-time.After(time.Until(deadline)):
+			done(ReasonTimeout)
+			return false
+		}
+	}
+}
+
+// waitChange waits for the next screen update.
+func (t *Terminal) waitChange(deadline time.Time) bool {
+	select {
+	case <-t.Changed():
+		return true
+	case <-t.done:
+		return false
+	case <-time.After(time.Until(deadline)):
+		return false
+	}
+}
+
+// trimLines strips trailing spaces from every line of a screen.
+func trimLines(screen string) string {
+	lines := strings.Split(screen, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// lastLine returns the last non-blank line of a screen and its row.
+func lastLine(screen string) (string, int) {
+	lines := strings.Split(screen, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if strings.TrimSpace(lines[i]) != "" {
+			return lines[i], i
+		}
+	}
+	return "", 0
+}
@@ -0,0 +1,137 @@
+package terminal
+
+import (
+	"regexp"
+	"testing"
+	"time"
+)
+
+// runExpect runs script under sh and drives it with a rule answering
+// "Continue?" with y.
+func runExpect(t *testing.T, script string, opts ExpectOptions) ExpectResult {
+	t.Helper()
+	term, err := New("sh", []string{"-c", script}, DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer term.Close()
+
+	if opts.Quiet == 0 {
+		opts.Quiet = 50 * time.Millisecond
+	}
+	if opts.Idle == 0 {
+		opts.Idle = 500 * time.Millisecond
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	rules := []ExpectRule{{Pattern: regexp.MustCompile(`Continue\?`), Send: "y\n"}}
+	return term.Expect(rules, opts)
+}
+
+func TestExpect(t *testing.T) {
+	tests := []struct {
+		name           string
+		script         string
+		opts           ExpectOptions
+		wantReason     string
+		wantIterations int
+	}{
+		{
+			name:           "prompt answered once",
+			script:         `printf 'Continue? '; read a; echo "got $a"; sleep 5`,
+			wantReason:     ExpectIdle,
+			wantIterations: 1,
+		},
+		{
+			name:           "prompt printed again on a new row",
+			script:         `for i in 1 2 3; do printf 'Continue? '; read a; done; echo finished; sleep 5`,
+			opts:           ExpectOptions{Until: regexp.MustCompile(`finished`)},
+			wantReason:     ExpectUntil,
+			wantIterations: 3,
+		},
+		{
+			name:           "prompt printed again in the same place",
+			script:         `for i in 1 2; do printf '\033[H\033[2JContinue? '; read a; done; printf '\033[H\033[2Jfinished'; sleep 5`,
+			opts:           ExpectOptions{Until: regexp.MustCompile(`finished`)},
+			wantReason:     ExpectUntil,
+			wantIterations: 2,
+		},
+		{
+			name:           "prompt left on screen while other output follows",
+			script:         `printf 'Continue? '; read a; sleep 0.2; echo tick; sleep 0.2; echo tock; sleep 5`,
+			wantReason:     ExpectIdle,
+			wantIterations: 1,
+		},
+		{
+			name:           "prompt redrawn without being written again",
+			script:         `printf 'Continue? '; read a; sleep 0.2; printf '\033[1;20Hstatus'; sleep 5`,
+			wantReason:     ExpectIdle,
+			wantIterations: 1,
+		},
+		{
+			name:           "no prompt",
+			script:         `echo hello; sleep 5`,
+			wantReason:     ExpectIdle,
+			wantIterations: 0,
+		},
+		{
+			name:           "command exits",
+			script:         `printf 'Continue? '; read a; echo bye`,
+			wantReason:     ExpectExited,
+			wantIterations: 1,
+		},
+		{
+			name:           "max iterations",
+			script:         `while :; do printf 'Continue? '; read a; done`,
+			opts:           ExpectOptions{MaxIterations: 3},
+			wantReason:     ExpectMaxIterations,
+			wantIterations: 3,
+		},
+		{
+			name:           "timeout",
+			script:         `sleep 5`,
+			opts:           ExpectOptions{Until: regexp.MustCompile(`never`), Timeout: 300 * time.Millisecond},
+			wantReason:     ExpectTimeout,
+			wantIterations: 0,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			result := runExpect(t, tt.script, tt.opts)
+			if result.Reason != tt.wantReason || result.Iterations != tt.wantIterations {
+				t.Errorf("Expect() = %s after %d firings, want %s after %d (%+v)",
+					result.Reason, result.Iterations, tt.wantReason, tt.wantIterations, result.Fired)
+			}
+			if len(result.Fired) != result.Iterations {
+				t.Errorf("Fired has %d entries for %d iterations", len(result.Fired), result.Iterations)
+			}
+		})
+	}
+}
+
+func TestExpectLastLine(t *testing.T) {
+	term, err := New("sh", []string{"-c", `echo 'Continue? (old)'; printf '> '; read a; echo "got $a"; sleep 5`}, DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer term.Close()
+
+	rules := []ExpectRule{
+		{Pattern: regexp.MustCompile(`Continue\?`), LastLine: true, Send: "wrong\n"},
+		{Pattern: regexp.MustCompile(`^>$`), LastLine: true, Send: "right\n"},
+	}
+	result := term.Expect(rules, ExpectOptions{
+		Until:   regexp.MustCompile(`got \w+`),
+		Quiet:   50 * time.Millisecond,
+		Timeout: 10 * time.Second,
+	})
+	if result.Reason != ExpectUntil || len(result.Fired) != 1 || result.Fired[0].Rule != 1 {
+		t.Fatalf("Expect() = %+v, want rule 1 firing once", result)
+	}
+	if err := term.WaitForText("got right", time.Second); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,32 @@
+package terminal
+
+import "time"
+
+// Frame is a screen state recorded with Options.RecordFrames.
+type Frame struct {
+	// Time is when the screen first looked like this.
+	Time   time.Time
+	Screen string
+	// Writes counts the batches of output that left the screen like this;
+	// more than one means the application drew the same screen again.
+	Writes int
+}
+
+// recordFrame appends the current screen to the recorded frames unless it
+// is the same as the last one. The caller must hold t.mu.
+func (t *Terminal) recordFrame() {
+	screen := t.vt.String()
+	if n := len(t.frames); n > 0 && t.frames[n-1].Screen == screen {
+		t.frames[n-1].Writes++
+		return
+	}
+	t.frames = append(t.frames, Frame{Time: time.Now(), Screen: screen, Writes: 1})
+}
+
+// Frames returns the distinct screens recorded so far, oldest first. It is
+// empty unless Options.RecordFrames was set.
+func (t *Terminal) Frames() []Frame {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Frame(nil), t.frames...)
+}
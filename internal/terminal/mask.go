@@ -0,0 +1,62 @@
+package terminal
+
+import (
+	"regexp"
+	"unicode/utf8"
+)
+
+// MaskRune replaces masked cells.
+const MaskRune = '░'
+
+// Mask selects volatile parts of the screen, such as a clock or a spinner,
+// that are ignored when comparing screens.
+type Mask struct {
+	Regions []Rect
+	// Patterns are matched against each row; the cells of every match are
+	// masked.
+	Patterns []*regexp.Regexp
+}
+
+// Empty reports whether the mask hides nothing.
+func (m Mask) Empty() bool {
+	return len(m.Regions) == 0 && len(m.Patterns) == 0
+}
+
+// Apply returns a copy of the snapshot with the masked cells' characters
+// replaced by MaskRune (colors and attributes are kept).
+func (m Mask) Apply(s Snapshot) Snapshot {
+	if m.Empty() {
+		return s
+	}
+	cells := make([][]Cell, len(s.Cells))
+	for y, row := range s.Cells {
+		cells[y] = append([]Cell(nil), row...)
+	}
+	masked := s
+	masked.Cells = cells
+
+	for _, r := range m.Regions {
+		r = s.clip(r)
+		for y := r.Row; y < r.Row+r.Height; y++ {
+			for x := r.Col; x < r.Col+r.Width; x++ {
+				cells[y][x].Char = MaskRune
+			}
+		}
+	}
+
+	// Patterns see the rows as they were, so a region cannot split a match
+	for y := range s.Cells {
+		line := s.Line(y)
+		for _, re := range m.Patterns {
+			for _, loc := range re.FindAllStringIndex(line, -1) {
+				// Byte offsets to cell columns
+				start := utf8.RuneCountInString(line[:loc[0]])
+				end := start + utf8.RuneCountInString(line[loc[0]:loc[1]])
+				for x := start; x < end && x < len(cells[y]); x++ {
+					cells[y][x].Char = MaskRune
+				}
+			}
+		}
+	}
+	return masked
+}
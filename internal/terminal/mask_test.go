@@ -0,0 +1,130 @@
+package terminal
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// textSnapshot builds a snapshot of the given lines, padded with spaces to
+// the widest one.
+func textSnapshot(lines ...string) Snapshot {
+	cols := 0
+	for _, line := range lines {
+		if n := len([]rune(line)); n > cols {
+			cols = n
+		}
+	}
+	s := Snapshot{Cols: cols, Rows: len(lines)}
+	for _, line := range lines {
+		runes := []rune(line)
+		row := make([]Cell, cols)
+		for x := range row {
+			row[x] = Cell{Char: ' ', FG: DefaultFG, BG: DefaultBG}
+			if x < len(runes) {
+				row[x].Char = runes[x]
+			}
+		}
+		s.Cells = append(s.Cells, row)
+	}
+	return s
+}
+
+func TestMaskApply(t *testing.T) {
+	patterns := func(exprs ...string) []*regexp.Regexp {
+		var res []*regexp.Regexp
+		for _, e := range exprs {
+			res = append(res, regexp.MustCompile(e))
+		}
+		return res
+	}
+	tests := []struct {
+		name  string
+		lines []string
+		mask  Mask
+		want  []string
+	}{
+		{
+			name:  "region",
+			lines: []string{"abcdef", "ghijkl"},
+			mask:  Mask{Regions: []Rect{{Row: 0, Col: 1, Width: 2, Height: 2}}},
+			want:  []string{"a░░def", "g░░jkl"},
+		},
+		{
+			name:  "region clipped at the right and bottom",
+			lines: []string{"abcdef", "ghijkl"},
+			mask:  Mask{Regions: []Rect{{Row: 1, Col: 4, Width: 100, Height: 100}}},
+			want:  []string{"abcdef", "ghij░░"},
+		},
+		{
+			name:  "region clipped at the left and top",
+			lines: []string{"abcdef", "ghijkl"},
+			mask:  Mask{Regions: []Rect{{Row: -1, Col: -2, Width: 4, Height: 2}}},
+			want:  []string{"░░cdef", "ghijkl"},
+		},
+		{
+			name:  "region off screen",
+			lines: []string{"abcdef"},
+			mask:  Mask{Regions: []Rect{{Row: 5, Col: 0, Width: 3, Height: 1}, {Row: 0, Col: 9, Width: 3, Height: 1}}},
+			want:  []string{"abcdef"},
+		},
+		{
+			name:  "pattern",
+			lines: []string{"time 12:30:01 ok"},
+			mask:  Mask{Patterns: patterns(`\d\d:\d\d:\d\d`)},
+			want:  []string{"time ░░░░░░░░ ok"},
+		},
+		{
+			name:  "pattern after multibyte text",
+			lines: []string{"✓ 日本 12:30 ok"},
+			mask:  Mask{Patterns: patterns(`\d\d:\d\d`)},
+			want:  []string{"✓ 日本 ░░░░░ ok"},
+		},
+		{
+			name:  "pattern matching multibyte text",
+			lines: []string{"[⠋⠙⠹] loading"},
+			mask:  Mask{Patterns: patterns(`[⠋⠙⠹]+`)},
+			want:  []string{"[░░░] loading"},
+		},
+		{
+			name:  "every match on every row",
+			lines: []string{"1s · 2s", "é 30s x"},
+			mask:  Mask{Patterns: patterns(`\d+s`)},
+			want:  []string{"░░ · ░░", "é ░░░ x"},
+		},
+		{
+			name:  "pattern sees the row before regions are masked",
+			lines: []string{"up 12:30"},
+			mask: Mask{
+				Regions:  []Rect{{Row: 0, Col: 3, Width: 1, Height: 1}},
+				Patterns: patterns(`\d\d:\d\d`),
+			},
+			want: []string{"up ░░░░░"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snap := textSnapshot(tt.lines...)
+			got := tt.mask.Apply(snap)
+			want := strings.Join(tt.want, "\n") + "\n"
+			if got.String() != want {
+				t.Errorf("Apply() =\n%s\nwant\n%s", got.String(), want)
+			}
+			if snap.String() != strings.Join(tt.lines, "\n")+"\n" {
+				t.Errorf("Apply() changed the original snapshot:\n%s", snap.String())
+			}
+		})
+	}
+}
+
+func TestMaskKeepsColors(t *testing.T) {
+	snap := textSnapshot("ab")
+	snap.Cells[0][0].FG, snap.Cells[0][0].Attr = 2, AttrBold
+	got := Mask{Regions: []Rect{{Row: 0, Col: 0, Width: 1, Height: 1}}}.Apply(snap)
+	if c := got.Cells[0][0]; c.Char != MaskRune || c.FG != 2 || c.Attr != AttrBold {
+		t.Errorf("masked cell = %+v, want %q with its color and attributes", c, MaskRune)
+	}
+	if (Mask{}).Apply(snap).String() != snap.String() {
+		t.Error("an empty mask changed the snapshot")
+	}
+}
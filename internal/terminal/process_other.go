@@ -0,0 +1,33 @@
+//go:build !unix
+
+package terminal
+
+import (
+	"os"
+	"syscall"
+)
+
+func newSysProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{}
+}
+
+// signalGroup signals only the command itself outside Unix, where there are
+// no process groups to signal.
+func (t *Terminal) signalGroup(sig syscall.Signal) error {
+	if t.cmd.Process == nil {
+		return nil
+	}
+	if sig == syscall.SIGKILL {
+		return t.cmd.Process.Kill()
+	}
+	return t.cmd.Process.Signal(sig)
+}
+
+// killProcess kills a single process.
+func killProcess(pid int) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return p.Kill()
+}
@@ -0,0 +1,27 @@
+//go:build unix
+
+package terminal
+
+import "syscall"
+
+// newSysProcAttr returns the attributes the command is started with. Its
+// stdout is always the PTY, so that is made the controlling terminal rather
+// than stdin (the default), which may have been redirected.
+func newSysProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Ctty: 1}
+}
+
+// signalGroup sends sig to the command's process group. The command is
+// started as a session leader (see pty.Start), so its pid is also its
+// process group id.
+func (t *Terminal) signalGroup(sig syscall.Signal) error {
+	if t.cmd.Process == nil {
+		return nil
+	}
+	return syscall.Kill(-t.cmd.Process.Pid, sig)
+}
+
+// killProcess kills a single process.
+func killProcess(pid int) error {
+	return syscall.Kill(pid, syscall.SIGKILL)
+}
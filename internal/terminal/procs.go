@@ -0,0 +1,195 @@
+package terminal
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// leftoverGrace is how long processes that were signalled along with the
+// command get to exit before they count as left over.
+const leftoverGrace = 200 * time.Millisecond
+
+// Process identifies a process started, directly or indirectly, by the
+// command.
+type Process struct {
+	PID     int    `json:"pid"`
+	Command string `json:"command"`
+
+	// start is the process start time, used to tell a process from a later
+	// one that reused its pid.
+	start uint64
+}
+
+// procStat is the part of /proc/<pid>/stat we use.
+type procStat struct {
+	pid     int
+	command string
+	state   byte
+	ppid    int
+	pgrp    int
+	session int
+	// start is the start time in clock ticks since boot.
+	start uint64
+}
+
+// readProcStat parses /proc/<pid>/stat. It fails on systems without procfs,
+// where process tracking is simply skipped.
+func readProcStat(pid int) (procStat, bool) {
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return procStat{}, false
+	}
+	// The command is in parentheses and may itself contain spaces and
+	// parentheses, so split after the last ')'.
+	s := string(data)
+	open, end := strings.IndexByte(s, '('), strings.LastIndexByte(s, ')')
+	if open < 0 || end < open {
+		return procStat{}, false
+	}
+	fields := strings.Fields(s[end+1:])
+	// fields[0] is the state (field 3); start time is field 22.
+	if len(fields) < 20 {
+		return procStat{}, false
+	}
+	st := procStat{pid: pid, command: s[open+1 : end], state: fields[0][0]}
+	st.start, _ = strconv.ParseUint(fields[19], 10, 64)
+	st.ppid, _ = strconv.Atoi(fields[1])
+	st.pgrp, _ = strconv.Atoi(fields[2])
+	st.session, _ = strconv.Atoi(fields[3])
+	return st, true
+}
+
+// allProcs lists every live (non-zombie) process.
+func allProcs() []procStat {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil
+	}
+	var procs []procStat
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		if st, ok := readProcStat(pid); ok && st.state != 'Z' {
+			procs = append(procs, st)
+		}
+	}
+	return procs
+}
+
+// holdsFile reports whether the process has path open.
+func holdsFile(pid int, path string) bool {
+	dir := "/proc/" + strconv.Itoa(pid) + "/fd"
+	fds, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	for _, fd := range fds {
+		if target, err := os.Readlink(dir + "/" + fd.Name()); err == nil && target == path {
+			return true
+		}
+	}
+	return false
+}
+
+// descendants returns the live processes in the command's session, process
+// group or process tree, or still attached to its PTY (such as daemons that
+// detached with setsid), excluding the command itself. Processes that
+// started before the command are skipped up front, so the costly check for
+// PTY holders only looks at the few that started since.
+func (t *Terminal) descendants() []Process {
+	if t.cmd.Process == nil {
+		return nil
+	}
+	root := t.cmd.Process.Pid
+	procs := allProcs()
+
+	children := make(map[int][]int)
+	for _, p := range procs {
+		children[p.ppid] = append(children[p.ppid], p.pid)
+	}
+	inTree := make(map[int]bool)
+	queue := []int{root}
+	if _, exited := t.ExitStatus(); exited {
+		// Once reaped, the pid may belong to an unrelated process; its
+		// children have been reparented anyway.
+		queue = nil
+	}
+	for len(queue) > 0 {
+		pid := queue[0]
+		queue = queue[1:]
+		for _, child := range children[pid] {
+			if !inTree[child] {
+				inTree[child] = true
+				queue = append(queue, child)
+			}
+		}
+	}
+
+	var found []Process
+	for _, p := range procs {
+		if p.pid == root || p.start < t.startTime {
+			continue
+		}
+		if inTree[p.pid] || p.session == root || p.pgrp == root ||
+			(t.ttyName != "" && p.pid != os.Getpid() && holdsFile(p.pid, t.ttyName)) {
+			found = append(found, Process{PID: p.pid, Command: commandLine(p), start: p.start})
+		}
+	}
+	return found
+}
+
+// commandLine returns the process's full command line, falling back to its
+// name (kernel threads and some zombies have none).
+func commandLine(p procStat) string {
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(p.pid) + "/cmdline")
+	if err != nil || len(data) == 0 {
+		return p.command
+	}
+	return strings.TrimSpace(strings.ReplaceAll(string(data), "\x00", " "))
+}
+
+// alive reports whether the process is still running (and is the same
+// process, not a reuse of its pid).
+func (p Process) alive() bool {
+	st, ok := readProcStat(p.PID)
+	return ok && st.state != 'Z' && st.start == p.start
+}
+
+// killLeftovers waits up to grace for the tracked processes, and any still
+// in the command's session, to exit. Those that do not are killed and
+// returned. The session is scanned once; after that only the candidates
+// found are polled.
+func (t *Terminal) killLeftovers(tracked []Process, grace time.Duration) []Process {
+	deadline := time.Now().Add(grace)
+	var candidates []Process
+	seen := make(map[int]bool)
+	for _, p := range append(append([]Process(nil), tracked...), t.descendants()...) {
+		if !seen[p.PID] {
+			seen[p.PID] = true
+			candidates = append(candidates, p)
+		}
+	}
+	for {
+		var running []Process
+		for _, p := range candidates {
+			if p.alive() {
+				running = append(running, p)
+			}
+		}
+		candidates = running
+		if len(running) == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			for _, p := range running {
+				_ = killProcess(p.PID)
+			}
+			return running
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,79 @@
+package terminal
+
+import (
+	"regexp"
+	"time"
+)
+
+// sandboxHelperArg marks a re-execution of the current program as the
+// sandbox helper (see RunSandboxHelper).
+const sandboxHelperArg = "__tui-goggles-sandbox"
+
+// Limits are resource limits applied to the command. Zero values mean no
+// limit.
+type Limits struct {
+	// CPU is the CPU time limit (RLIMIT_CPU), rounded up to whole seconds.
+	// The command receives SIGXCPU when it is reached and SIGKILL a second
+	// later.
+	CPU time.Duration `json:"cpu,omitempty"`
+	// Memory is the address space limit in bytes (RLIMIT_AS).
+	Memory uint64 `json:"memory,omitempty"`
+	// OpenFiles is the open file descriptor limit (RLIMIT_NOFILE).
+	OpenFiles uint64 `json:"open_files,omitempty"`
+	// Processes is the process count limit (RLIMIT_NPROC). It counts every
+	// process of the user and is not enforced for root.
+	Processes uint64 `json:"processes,omitempty"`
+}
+
+func (l Limits) enabled() bool {
+	return l != Limits{}
+}
+
+// Sandbox selects Linux namespace isolation for the command.
+type Sandbox struct {
+	// NoNetwork runs the command in an empty network namespace.
+	NoNetwork bool `json:"no_network,omitempty"`
+	// PrivateTmp mounts an empty tmpfs on /tmp.
+	PrivateTmp bool `json:"private_tmp,omitempty"`
+	// ReadOnly paths are bind-mounted read-only over themselves.
+	ReadOnly []string `json:"read_only,omitempty"`
+}
+
+func (s Sandbox) enabled() bool {
+	return s.NoNetwork || s.PrivateTmp || len(s.ReadOnly) > 0
+}
+
+// sandboxConfig is passed to the sandbox helper.
+type sandboxConfig struct {
+	Limits  Limits  `json:"limits"`
+	Sandbox Sandbox `json:"sandbox"`
+}
+
+// Limit breach kinds reported by LimitReport.
+const (
+	BreachCPU    = "cpu"
+	BreachMemory = "memory"
+)
+
+// LimitReport describes the command's resource usage against its limits.
+type LimitReport struct {
+	CPULimitMs       int64  `json:"cpu_limit_ms,omitempty"`
+	MemoryLimitBytes uint64 `json:"memory_limit_bytes,omitempty"`
+	OpenFilesLimit   uint64 `json:"open_files_limit,omitempty"`
+	ProcessesLimit   uint64 `json:"processes_limit,omitempty"`
+
+	CPUMs       int64 `json:"cpu_ms"`
+	MaxRSSBytes int64 `json:"max_rss_bytes"`
+	// Breached lists the limits the command ran into. CPU breaches are
+	// detected from the terminating signal; memory breaches when the
+	// command failed after reporting that it ran out of memory.
+	Breached []string `json:"breached,omitempty"`
+	// LikelyBreached lists limits the command probably ran into: memory
+	// when it crashed (SIGSEGV, SIGABRT or SIGBUS) without saying why, as
+	// programs that do not check for failed allocations do.
+	LikelyBreached []string `json:"likely_breached,omitempty"`
+}
+
+// outOfMemory matches the messages common runtimes print when an allocation
+// fails (Go, Python, C++, Rust, Java, Node and the C library's strerror).
+var outOfMemory = regexp.MustCompile(`(?i)out ?of ?memory|cannot allocate memory|MemoryError|bad_alloc|memory allocation of \d+ bytes failed`)
@@ -0,0 +1,263 @@
+package terminal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// rlimitNproc is RLIMIT_NPROC, which the syscall package does not define.
+const rlimitNproc = 6
+
+// wrapSandbox makes cmd start through the sandbox helper: the current
+// program is re-executed in new namespaces, applies the limits and mounts,
+// then executes the real command in its place (keeping the pid, session
+// and PTY).
+func wrapSandbox(cmd *exec.Cmd, limits Limits, sandbox Sandbox) error {
+	if cmd.Err != nil {
+		return cmd.Err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("locating sandbox helper: %w", err)
+	}
+	config, err := json.Marshal(sandboxConfig{Limits: limits, Sandbox: sandbox})
+	if err != nil {
+		return err
+	}
+
+	cmd.Args = append([]string{exe, sandboxHelperArg, string(config), cmd.Path}, cmd.Args...)
+	cmd.Path = exe
+
+	if !sandbox.enabled() {
+		return nil
+	}
+	cmd.SysProcAttr.Cloneflags = syscall.CLONE_NEWNS
+	if sandbox.NoNetwork {
+		cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWNET
+	}
+	if uid := os.Getuid(); uid != 0 {
+		// Unprivileged: a user namespace in which we are root grants the
+		// rights to mount and create the other namespaces.
+		cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWUSER
+		cmd.SysProcAttr.UidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: uid, Size: 1}}
+		cmd.SysProcAttr.GidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}}
+	}
+	return nil
+}
+
+// RunSandboxHelper must be called at the start of main by programs that use
+// Options.Limits or Options.Sandbox. When the program was re-executed as the
+// sandbox helper it sets up the sandbox and executes the command, never
+// returning; otherwise it returns immediately.
+func RunSandboxHelper() {
+	if len(os.Args) < 5 || os.Args[1] != sandboxHelperArg {
+		return
+	}
+	err := runSandboxHelper(os.Args[2], os.Args[3], os.Args[4:])
+	// Only reached on failure; stderr is the PTY, so this shows on screen.
+	fmt.Fprintf(os.Stderr, "tui-goggles sandbox: %v\n", err)
+	os.Exit(127)
+}
+
+func runSandboxHelper(config, path string, argv []string) error {
+	var cfg sandboxConfig
+	if err := json.Unmarshal([]byte(config), &cfg); err != nil {
+		return fmt.Errorf("invalid sandbox config: %w", err)
+	}
+
+	if cfg.Sandbox.enabled() {
+		if err := setupMounts(cfg.Sandbox); err != nil {
+			return err
+		}
+	}
+	if err := applyLimits(cfg.Limits); err != nil {
+		return err
+	}
+	return syscall.Exec(path, argv, os.Environ())
+}
+
+// setupMounts applies the sandbox's mounts in the (new, private) mount
+// namespace.
+func setupMounts(s Sandbox) error {
+	// Keep our mounts from propagating back to the host.
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("making mounts private: %w", err)
+	}
+
+	if s.PrivateTmp {
+		if err := syscall.Mount("tmpfs", "/tmp", "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "mode=1777"); err != nil {
+			return fmt.Errorf("mounting private /tmp: %w", err)
+		}
+		// A HOME under /tmp (as with -clean-env) is now hidden; recreate
+		// it empty.
+		if home := os.Getenv("HOME"); home != "" && strings.HasPrefix(filepath.Clean(home), "/tmp/") {
+			if err := os.MkdirAll(home, 0700); err != nil {
+				return fmt.Errorf("creating HOME in private /tmp: %w", err)
+			}
+		}
+	}
+
+	for _, path := range s.ReadOnly {
+		if err := syscall.Mount(path, path, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+			return fmt.Errorf("bind-mounting %s: %w", path, err)
+		}
+		// A remount must keep the flags the kernel locked on the original
+		// mount, or it is refused inside a user namespace.
+		var st syscall.Statfs_t
+		if err := syscall.Statfs(path, &st); err != nil {
+			return fmt.Errorf("inspecting %s: %w", path, err)
+		}
+		flags := uintptr(syscall.MS_BIND | syscall.MS_REMOUNT | syscall.MS_RDONLY)
+		flags |= uintptr(st.Flags) & (syscall.MS_NOSUID | syscall.MS_NODEV | syscall.MS_NOEXEC)
+		if err := syscall.Mount("", path, "", flags, ""); err != nil {
+			return fmt.Errorf("making %s read-only: %w", path, err)
+		}
+	}
+
+	// The working directory still refers to the directory under the new
+	// mounts; enter it again through them.
+	if wd, err := os.Getwd(); err == nil {
+		if err := os.Chdir(wd); err != nil {
+			return fmt.Errorf("entering working directory: %w", err)
+		}
+	}
+	return nil
+}
+
+// applyLimits sets the resource limits, which the command inherits.
+func applyLimits(l Limits) error {
+	if l.CPU > 0 {
+		secs := uint64((l.CPU + 999999999) / 1000000000)
+		// SIGXCPU at the soft limit, SIGKILL a second later.
+		if err := setrlimit(syscall.RLIMIT_CPU, secs, secs+1); err != nil {
+			return fmt.Errorf("setting CPU limit: %w", err)
+		}
+	}
+	if l.Memory > 0 {
+		if err := setrlimit(syscall.RLIMIT_AS, l.Memory, l.Memory); err != nil {
+			return fmt.Errorf("setting memory limit: %w", err)
+		}
+	}
+	if l.OpenFiles > 0 {
+		if err := setrlimit(syscall.RLIMIT_NOFILE, l.OpenFiles, l.OpenFiles); err != nil {
+			return fmt.Errorf("setting open files limit: %w", err)
+		}
+	}
+	if l.Processes > 0 {
+		if err := setrlimit(rlimitNproc, l.Processes, l.Processes); err != nil {
+			return fmt.Errorf("setting process limit: %w", err)
+		}
+	}
+	return nil
+}
+
+// setrlimit sets a limit, keeping the hard limit no higher than it already
+// is (raising it needs privileges).
+func setrlimit(resource int, soft, hard uint64) error {
+	var cur syscall.Rlimit
+	if err := syscall.Getrlimit(resource, &cur); err != nil {
+		return err
+	}
+	if hard > cur.Max {
+		hard = cur.Max
+	}
+	if soft > hard {
+		soft = hard
+	}
+	return syscall.Setrlimit(resource, &syscall.Rlimit{Cur: soft, Max: hard})
+}
+
+// LimitReport returns the command's resource usage against its limits once
+// it has exited, or nil if it had no limits or is still running.
+func (t *Terminal) LimitReport() *LimitReport {
+	if !t.limits.enabled() {
+		return nil
+	}
+	select {
+	case <-t.exited:
+	default:
+		return nil
+	}
+
+	report := &LimitReport{
+		CPULimitMs:       t.limits.CPU.Milliseconds(),
+		MemoryLimitBytes: t.limits.Memory,
+		OpenFilesLimit:   t.limits.OpenFiles,
+		ProcessesLimit:   t.limits.Processes,
+	}
+	state := t.cmd.ProcessState
+	if state == nil {
+		return report
+	}
+	cpu := state.UserTime() + state.SystemTime()
+	report.CPUMs = cpu.Milliseconds()
+	if usage, ok := state.SysUsage().(*syscall.Rusage); ok {
+		report.MaxRSSBytes = usage.Maxrss * 1024
+	}
+
+	status, _ := state.Sys().(syscall.WaitStatus)
+	if t.limits.CPU > 0 && status.Signaled() {
+		sig := status.Signal()
+		if sig == syscall.SIGXCPU || (sig == syscall.SIGKILL && cpu >= t.limits.CPU) {
+			report.Breached = append(report.Breached, BreachCPU)
+		}
+	}
+	if t.limits.Memory > 0 {
+		// The message saying why the command failed may still be on its way
+		// through the PTY
+		select {
+		case <-t.done:
+		case <-time.After(outputDrainWait):
+		}
+		t.mu.Lock()
+		output := plainOutput(t.output)
+		t.mu.Unlock()
+		switch memoryBreach(status, output) {
+		case breachDefinite:
+			report.Breached = append(report.Breached, BreachMemory)
+		case breachLikely:
+			report.LikelyBreached = append(report.LikelyBreached, BreachMemory)
+		}
+	}
+	return report
+}
+
+// outputDrainWait bounds how long LimitReport waits for the last output of
+// a command that has exited (processes it started may keep the PTY open).
+const outputDrainWait = 200 * time.Millisecond
+
+// How sure memoryBreach is that the memory limit was hit.
+const (
+	breachNone = iota
+	breachLikely
+	breachDefinite
+)
+
+// memoryBreach judges from how the command ended and the tail of its output
+// whether it ran out of memory. A failed allocation is an error the program
+// sees, not a signal, so this relies on the program saying so, or on the
+// crash an unchecked allocation leads to.
+func memoryBreach(status syscall.WaitStatus, output string) int {
+	crashed := false
+	if status.Signaled() {
+		switch status.Signal() {
+		case syscall.SIGSEGV, syscall.SIGABRT, syscall.SIGBUS:
+			crashed = true
+		}
+	}
+	failed := crashed || (status.Exited() && status.ExitStatus() != 0)
+	switch {
+	case failed && outOfMemory.MatchString(output):
+		return breachDefinite
+	case crashed:
+		return breachLikely
+	default:
+		return breachNone
+	}
+}
@@ -0,0 +1,68 @@
+package terminal
+
+import (
+	"os"
+	"slices"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestMemoryBreach(t *testing.T) {
+	exited := func(code int) syscall.WaitStatus { return syscall.WaitStatus(code << 8) }
+	signaled := func(sig syscall.Signal) syscall.WaitStatus { return syscall.WaitStatus(sig) }
+
+	tests := []struct {
+		name   string
+		status syscall.WaitStatus
+		output string
+		want   int
+	}{
+		{"success", exited(0), "", breachNone},
+		{"success mentioning memory", exited(0), "out of memory: 0 pages", breachNone},
+		{"plain failure", exited(1), "file not found", breachNone},
+		{"go", exited(2), "fatal error: runtime: out of memory\n", breachDefinite},
+		{"python", exited(1), "Traceback (most recent call last):\nMemoryError\n", breachDefinite},
+		{"c++", signaled(syscall.SIGABRT), "what():  std::bad_alloc\n", breachDefinite},
+		{"rust", signaled(syscall.SIGABRT), "memory allocation of 1048576 bytes failed\n", breachDefinite},
+		{"java", exited(1), "java.lang.OutOfMemoryError: Java heap space", breachDefinite},
+		{"strerror", exited(1), "mmap: Cannot allocate memory", breachDefinite},
+		{"silent crash", signaled(syscall.SIGSEGV), "", breachLikely},
+		{"abort", signaled(syscall.SIGABRT), "assertion failed", breachLikely},
+		{"terminated", signaled(syscall.SIGTERM), "", breachNone},
+		{"killed", signaled(syscall.SIGKILL), "", breachNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := memoryBreach(tt.status, tt.output); got != tt.want {
+				t.Errorf("memoryBreach() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimitReportMemory(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	term, err := New(exe, nil, Options{
+		Env:    []string{allocEnv + "=1"},
+		Limits: Limits{Memory: 1 << 30},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer term.Close()
+
+	if _, err := term.WaitExit(10 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	report := term.LimitReport()
+	if report == nil {
+		t.Fatal("LimitReport() = nil")
+	}
+	if !slices.Contains(report.Breached, BreachMemory) {
+		t.Errorf("Breached = %v, want %q\n%s", report.Breached, BreachMemory, term.Screenshot())
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+
+package terminal
+
+import (
+	"errors"
+	"os/exec"
+)
+
+func wrapSandbox(cmd *exec.Cmd, limits Limits, sandbox Sandbox) error {
+	return errors.New("resource limits and sandboxing are only supported on Linux")
+}
+
+// RunSandboxHelper is a no-op outside Linux, where sandboxing is not
+// supported.
+func RunSandboxHelper() {}
+
+// LimitReport always returns nil outside Linux.
+func (t *Terminal) LimitReport() *LimitReport {
+	return nil
+}
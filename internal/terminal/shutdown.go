@@ -0,0 +1,106 @@
+package terminal
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// Shutdown stages, in the order they are tried.
+const (
+	// StageExited means the command had already exited on its own.
+	StageExited = "exited"
+	// StageKeys means the command exited after the policy's keys were sent.
+	StageKeys = "keys"
+	// StageSIGTERM means the command exited after SIGTERM.
+	StageSIGTERM = "sigterm"
+	// StageSIGKILL means the command had to be killed.
+	StageSIGKILL = "sigkill"
+)
+
+// ShutdownPolicy describes how Shutdown ends the command. Each stage only
+// runs if the command is still running after the previous one.
+type ShutdownPolicy struct {
+	// Keys are sent first so the application can quit cleanly (e.g. "q").
+	// No keys skips the stage.
+	Keys string
+	// KeysWait is how long to wait for the command to exit after Keys.
+	KeysWait time.Duration
+	// TermWait is how long to wait after SIGTERM before sending SIGKILL.
+	TermWait time.Duration
+}
+
+// ShutdownResult reports how the command ended.
+type ShutdownResult struct {
+	Stage    string `json:"stage"`
+	ExitCode int    `json:"exit_code"`
+	// Signal names the signal that terminated the command, if any.
+	Signal     string `json:"signal,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+	// Leftovers are processes started by the command that were still
+	// running once it had ended; they are killed.
+	Leftovers []Process `json:"leftovers,omitempty"`
+}
+
+// Shutdown ends the command according to the policy: keys, then SIGTERM,
+// then SIGKILL. Signals go to the command's whole process group, so
+// processes it spawned are terminated with it; any that survive, or that
+// left the group, are killed and reported as leftovers.
+func (t *Terminal) Shutdown(policy ShutdownPolicy) ShutdownResult {
+	start := time.Now()
+	tracked := t.descendants()
+	result := func(stage string) ShutdownResult {
+		code, _ := t.ExitStatus()
+		return ShutdownResult{
+			Stage:      stage,
+			ExitCode:   code,
+			Signal:     t.exitSignal(),
+			DurationMs: time.Since(start).Milliseconds(),
+			Leftovers:  t.killLeftovers(tracked, leftoverGrace),
+		}
+	}
+
+	if _, exited := t.ExitStatus(); exited {
+		return result(StageExited)
+	}
+
+	if policy.Keys != "" && t.SendKeys(policy.Keys) == nil && t.waitExited(policy.KeysWait) {
+		return result(StageKeys)
+	}
+
+	if t.signalGroup(syscall.SIGTERM) == nil && t.waitExited(policy.TermWait) {
+		return result(StageSIGTERM)
+	}
+
+	_ = t.signalGroup(syscall.SIGKILL)
+	<-t.exited
+	return result(StageSIGKILL)
+}
+
+// exitSignal returns the name of the signal that terminated the command, or
+// "" if it exited normally (or has not exited).
+func (t *Terminal) exitSignal() string {
+	t.mu.Lock()
+	err := t.exitErr
+	t.mu.Unlock()
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return ""
+	}
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return status.Signal().String()
+	}
+	return ""
+}
+
+// waitExited reports whether the command exits within timeout.
+func (t *Terminal) waitExited(timeout time.Duration) bool {
+	select {
+	case <-t.exited:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
@@ -0,0 +1,155 @@
+package terminal
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShutdownStages(t *testing.T) {
+	tests := []struct {
+		name       string
+		script     string
+		policy     ShutdownPolicy
+		wantStage  string
+		wantCode   int
+		wantSignal string
+	}{
+		{
+			name:      "already exited",
+			script:    `echo ready; exit 3`,
+			wantStage: StageExited,
+			wantCode:  3,
+		},
+		{
+			name:      "quits on keys",
+			script:    `echo ready; read a; exit 0`,
+			policy:    ShutdownPolicy{Keys: "q\n", KeysWait: 5 * time.Second, TermWait: 5 * time.Second},
+			wantStage: StageKeys,
+		},
+		{
+			name:       "ignores keys, ends on SIGTERM",
+			script:     `echo ready; exec sleep 100`,
+			policy:     ShutdownPolicy{Keys: "q\n", KeysWait: 200 * time.Millisecond, TermWait: 5 * time.Second},
+			wantStage:  StageSIGTERM,
+			wantCode:   -1,
+			wantSignal: "terminated",
+		},
+		{
+			name:       "ignores SIGTERM",
+			script:     `trap '' TERM; echo ready; while :; do sleep 0.05; done`,
+			policy:     ShutdownPolicy{TermWait: 300 * time.Millisecond},
+			wantStage:  StageSIGKILL,
+			wantCode:   -1,
+			wantSignal: "killed",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			term, err := New("sh", []string{"-c", tt.script}, DefaultOptions())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer term.Close()
+			if err := term.WaitForText("ready", 5*time.Second); err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantStage == StageExited {
+				if _, err := term.WaitExit(5 * time.Second); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			result := term.Shutdown(tt.policy)
+			if result.Stage != tt.wantStage || result.ExitCode != tt.wantCode || result.Signal != tt.wantSignal {
+				t.Errorf("Shutdown() = stage %q, exit %d, signal %q; want %q, %d, %q",
+					result.Stage, result.ExitCode, result.Signal, tt.wantStage, tt.wantCode, tt.wantSignal)
+			}
+			if len(result.Leftovers) != 0 {
+				t.Errorf("Leftovers = %+v, want none", result.Leftovers)
+			}
+		})
+	}
+}
+
+// checkLeftover checks that leftovers report exactly the one sleep the
+// script left behind, and that it has been killed.
+func checkLeftover(t *testing.T, leftovers []Process) {
+	t.Helper()
+	if len(leftovers) != 1 || !strings.Contains(leftovers[0].Command, "sleep 100") {
+		t.Fatalf("Leftovers = %+v, want the sleep", leftovers)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for leftovers[0].alive() {
+		if time.Now().After(deadline) {
+			t.Fatalf("leftover %d is still running", leftovers[0].PID)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestShutdownLeftovers(t *testing.T) {
+	tests := []struct {
+		name      string
+		script    string
+		wantStage string
+	}{
+		// nohup keeps the sleep alive when the session leader exits (once it
+		// has had time to start)
+		{"left behind by an exited command", `nohup sleep 100 >/dev/null 2>&1 & sleep 0.2; echo ready`, StageExited},
+		// A daemon in a session of its own is found by the PTY it holds
+		{"detached with setsid", `setsid sleep 100 & sleep 0.2; echo ready`, StageExited},
+		// An ignored signal stays ignored across exec, so SIGTERM to the
+		// group does not end the sleep
+		{"survives SIGTERM", `(trap '' TERM HUP; exec sleep 100) & echo ready; wait`, StageSIGTERM},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			term, err := New("sh", []string{"-c", tt.script}, DefaultOptions())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer term.Close()
+			if err := term.WaitForText("ready", 5*time.Second); err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantStage == StageExited {
+				if _, err := term.WaitExit(5 * time.Second); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			result := term.Shutdown(ShutdownPolicy{TermWait: 5 * time.Second})
+			if result.Stage != tt.wantStage {
+				t.Errorf("Stage = %q, want %q", result.Stage, tt.wantStage)
+			}
+			checkLeftover(t, result.Leftovers)
+		})
+	}
+}
+
+func TestCloseKillsLeftovers(t *testing.T) {
+	term, err := New("sh", []string{"-c", `nohup sleep 100 >/dev/null 2>&1 & sleep 0.2; echo ready; sleep 100`}, DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := term.WaitForText("ready", 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	leftovers := term.descendants()
+	if err := term.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range leftovers {
+		if p.alive() {
+			t.Errorf("process %d (%s) survived Close", p.PID, p.Command)
+		}
+	}
+	if len(leftovers) == 0 {
+		t.Error("descendants() found none of the command's processes")
+	}
+}
@@ -0,0 +1,194 @@
+package terminal
+
+import (
+	"strings"
+
+	"github.com/hinshun/vt10x"
+)
+
+// Attr is a bit set of character attributes. The bit layout mirrors vt10x's
+// internal glyph mode so values can be converted directly.
+type Attr int16
+
+// Character attributes.
+const (
+	AttrReverse Attr = 1 << iota
+	AttrUnderline
+	AttrBold
+	AttrGfx
+	AttrItalic
+	AttrBlink
+	AttrWrap
+)
+
+// Color is a terminal color: 0-15 are the ANSI colors, 16-255 the xterm
+// palette, and larger values are 24-bit RGB or one of the defaults below.
+type Color uint32
+
+// Default colors used when the application has not set one.
+const (
+	DefaultFG = Color(vt10x.DefaultFG)
+	DefaultBG = Color(vt10x.DefaultBG)
+)
+
+// Cell is a single character cell of the virtual screen. FG and BG are the
+// colors as displayed, i.e. already swapped for reverse-video cells.
+type Cell struct {
+	Char rune
+	FG   Color
+	BG   Color
+	Attr Attr
+}
+
+// Has reports whether the cell has the given attribute set.
+func (c Cell) Has(a Attr) bool {
+	return c.Attr&a != 0
+}
+
+// Snapshot is a consistent copy of the screen grid and cursor state.
+type Snapshot struct {
+	Cols          int
+	Rows          int
+	Cells         [][]Cell // indexed [row][col]
+	CursorCol     int
+	CursorRow     int
+	CursorVisible bool
+}
+
+// Snapshot captures the full cell grid, including colors and attributes.
+func (t *Terminal) Snapshot() Snapshot {
+	snap := t.Peek()
+	if t.trace != nil {
+		t.trace.emit(TraceEvent{Event: TraceSnapshot, Text: snap.String()})
+	}
+	return snap
+}
+
+// Peek is like Snapshot but is not traced, for live views that redraw on
+// every change.
+func (t *Terminal) Peek() Snapshot {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.vt.Lock()
+	defer t.vt.Unlock()
+
+	cols, rows := t.vt.Size()
+	cells := make([][]Cell, rows)
+	for y := 0; y < rows; y++ {
+		cells[y] = make([]Cell, cols)
+		for x := 0; x < cols; x++ {
+			g := t.vt.Cell(x, y)
+			cells[y][x] = Cell{
+				Char: g.Char,
+				FG:   Color(g.FG),
+				BG:   Color(g.BG),
+				Attr: Attr(g.Mode),
+			}
+		}
+	}
+
+	cursor := t.vt.Cursor()
+	return Snapshot{
+		Cols:          cols,
+		Rows:          rows,
+		Cells:         cells,
+		CursorCol:     cursor.X,
+		CursorRow:     cursor.Y,
+		CursorVisible: t.vt.CursorVisible(),
+	}
+}
+
+// Line returns the text of a single row.
+func (s Snapshot) Line(row int) string {
+	if row < 0 || row >= len(s.Cells) {
+		return ""
+	}
+	runes := make([]rune, len(s.Cells[row]))
+	for x, c := range s.Cells[row] {
+		runes[x] = c.Char
+	}
+	return string(runes)
+}
+
+// String renders the grid as text, one line per row, in the same form as
+// Screenshot.
+func (s Snapshot) String() string {
+	var sb strings.Builder
+	for y := range s.Cells {
+		sb.WriteString(s.Line(y))
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// Rect is a rectangular area of the screen, in cells.
+type Rect struct {
+	Row    int `json:"row"`
+	Col    int `json:"col"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// clip limits r to the snapshot's bounds.
+func (s Snapshot) clip(r Rect) Rect {
+	if r.Row < 0 {
+		r.Height += r.Row
+		r.Row = 0
+	}
+	if r.Col < 0 {
+		r.Width += r.Col
+		r.Col = 0
+	}
+	if r.Row > s.Rows {
+		r.Row = s.Rows
+	}
+	if r.Col > s.Cols {
+		r.Col = s.Cols
+	}
+	if r.Row+r.Height > s.Rows {
+		r.Height = s.Rows - r.Row
+	}
+	if r.Col+r.Width > s.Cols {
+		r.Width = s.Cols - r.Col
+	}
+	if r.Width < 0 {
+		r.Width = 0
+	}
+	if r.Height < 0 {
+		r.Height = 0
+	}
+	return r
+}
+
+// Crop returns the part of the snapshot inside r. The cursor position is
+// made relative to the crop and marked invisible if it falls outside it.
+func (s Snapshot) Crop(r Rect) Snapshot {
+	r = s.clip(r)
+
+	cells := make([][]Cell, r.Height)
+	for y := 0; y < r.Height; y++ {
+		row := s.Cells[r.Row+y]
+		cells[y] = append([]Cell(nil), row[r.Col:r.Col+r.Width]...)
+	}
+
+	cropped := Snapshot{
+		Cols:          r.Width,
+		Rows:          r.Height,
+		Cells:         cells,
+		CursorCol:     s.CursorCol - r.Col,
+		CursorRow:     s.CursorRow - r.Row,
+		CursorVisible: s.CursorVisible,
+	}
+	if cropped.CursorCol < 0 || cropped.CursorCol >= r.Width ||
+		cropped.CursorRow < 0 || cropped.CursorRow >= r.Height {
+		cropped.CursorVisible = false
+	}
+	return cropped
+}
+
+// RegionText returns the text inside r, one line per row, without a
+// trailing newline.
+func (s Snapshot) RegionText(r Rect) string {
+	return strings.TrimSuffix(s.Crop(r).String(), "\n")
+}
@@ -4,6 +4,7 @@ package terminal
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
 	"math"
@@ -11,6 +12,7 @@ import (
 	"os/exec"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/creack/pty"
@@ -20,6 +22,9 @@ import (
 // maxTerminalDimension is the maximum allowed terminal size to prevent overflow.
 const maxTerminalDimension = math.MaxUint16
 
+// closeTimeout bounds how long Close waits for the PTY reader to stop.
+const closeTimeout = 2 * time.Second
+
 // Terminal wraps a PTY and virtual terminal emulator to capture TUI output.
 type Terminal struct {
 	cmd     *exec.Cmd
@@ -30,6 +35,36 @@ type Terminal struct {
 	mu      sync.Mutex
 	done    chan struct{}
 	err     error
+
+	exited  chan struct{}
+	exitErr error
+
+	limits     Limits
+	trace      *tracer
+	stableMask Mask
+	// changed is closed (and replaced) whenever output updates the screen;
+	// guarded by mu, as is lastChange, when that last happened.
+	changed    chan struct{}
+	lastChange time.Time
+	// frames are the distinct screens seen when recordFrames is set;
+	// guarded by mu.
+	recordFrames bool
+	frames       []Frame
+	// output is the tail of the output read so far and outputTotal its
+	// total length, so Expect can tell what was written after a point;
+	// guarded by mu.
+	output      []byte
+	outputTotal int64
+	// responses are the replies to the query being handled; only used by
+	// the readLoop goroutine.
+	responses []string
+
+	// ttyName is the PTY's device path as seen by the command (empty if
+	// unknown), used to find processes still attached to it.
+	ttyName string
+	// startTime is the command's start time in clock ticks since boot (0
+	// without procfs); no process started earlier can be one of its own.
+	startTime uint64
 }
 
 // Options configures the terminal emulator.
@@ -37,6 +72,35 @@ type Options struct {
 	Rows int
 	Cols int
 	Env  []string
+	// CleanEnv starts the command with only Env (plus TERM) instead of
+	// inheriting the current process environment.
+	CleanEnv bool
+	// Dir is the command's working directory (the current one if empty).
+	Dir string
+	// Stdin, if set, replaces the PTY as the command's standard input, for
+	// example a pipe or file. The PTY stays its controlling terminal, so
+	// keys can still be read from /dev/tty.
+	Stdin *os.File
+	// Stderr, if set, receives the command's standard error instead of the
+	// PTY, so it does not end up on the screen.
+	Stderr *os.File
+	// ExtraFiles are passed to the command as file descriptors 3, 4, ...
+	ExtraFiles []*os.File
+	// Limits and Sandbox restrict the command (Linux only). The program
+	// must call RunSandboxHelper at the start of main to use them.
+	Limits  Limits
+	Sandbox Sandbox
+	// Trace, if set, receives a JSON-lines log of everything read, every
+	// intercepted query and response, every key sent and every wait.
+	Trace io.Writer
+	// StableMask hides volatile parts of the screen (a clock, a spinner)
+	// from WaitForStable, so they do not keep the screen from settling.
+	StableMask Mask
+	// RecordFrames snapshots the screen after every batch of output read
+	// from the command, so states too brief for the waits to see (an error
+	// flashed before a redraw) can be inspected afterwards with Frames. A
+	// redraw spanning several reads shows up as several frames.
+	RecordFrames bool
 }
 
 // DefaultOptions returns sensible defaults for terminal size.
@@ -65,8 +129,26 @@ func New(command string, args []string, opts Options) (*Terminal, error) {
 	}
 
 	cmd := exec.Command(command, args...)
-	cmd.Env = append(os.Environ(), opts.Env...)
+	if opts.CleanEnv {
+		cmd.Env = append([]string{}, opts.Env...)
+	} else {
+		cmd.Env = append(os.Environ(), opts.Env...)
+	}
 	cmd.Env = append(cmd.Env, "TERM=xterm-256color")
+	cmd.Dir = opts.Dir
+	cmd.ExtraFiles = opts.ExtraFiles
+	if opts.Stdin != nil {
+		cmd.Stdin = opts.Stdin
+	}
+	if opts.Stderr != nil {
+		cmd.Stderr = opts.Stderr
+	}
+	cmd.SysProcAttr = newSysProcAttr()
+	if opts.Limits.enabled() || opts.Sandbox.enabled() {
+		if err := wrapSandbox(cmd, opts.Limits, opts.Sandbox); err != nil {
+			return nil, fmt.Errorf("failed to set up sandbox: %w", err)
+		}
+	}
 
 	// Start command with PTY first so we can use it as the vt10x writer
 	ptmx, err := pty.StartWithSize(cmd, &pty.Winsize{
@@ -79,26 +161,68 @@ func New(command string, args []string, opts Options) (*Terminal, error) {
 
 	// Create virtual terminal with PTY as writer for built-in query responses
 	// vt10x will automatically respond to DSR (ESC[5n, ESC[6n) queries
-	vt := vt10x.New(
+	t := &Terminal{
+		cmd:        cmd,
+		ptyFile:    ptmx,
+		rows:       opts.Rows,
+		cols:       opts.Cols,
+		done:       make(chan struct{}),
+		exited:     make(chan struct{}),
+		changed:    make(chan struct{}),
+		lastChange: time.Now(),
+		limits:     opts.Limits,
+		trace:      newTracer(opts.Trace),
+		stableMask: opts.StableMask,
+
+		recordFrames: opts.RecordFrames,
+	}
+	t.vt = vt10x.New(
 		vt10x.WithSize(opts.Cols, opts.Rows),
-		vt10x.WithWriter(ptmx),
+		vt10x.WithWriter(responseWriter{t}),
 	)
 
-	t := &Terminal{
-		cmd:     cmd,
-		ptyFile: ptmx,
-		vt:      vt,
-		rows:    opts.Rows,
-		cols:    opts.Cols,
-		done:    make(chan struct{}),
+	// The command's stdout is always the PTY
+	t.ttyName, _ = os.Readlink(fmt.Sprintf("/proc/%d/fd/1", cmd.Process.Pid))
+	if st, ok := readProcStat(cmd.Process.Pid); ok {
+		t.startTime = st.start
 	}
 
 	// Start reading from PTY and feeding to virtual terminal
 	go t.readLoop()
+	go t.waitLoop()
 
 	return t, nil
 }
 
+// Env returns the environment the command was started with. Later entries
+// override earlier ones with the same name.
+func (t *Terminal) Env() []string {
+	return append([]string(nil), t.cmd.Env...)
+}
+
+// Changed returns a channel that is closed the next time output updates the
+// screen. Call it again after each notification to keep watching.
+func (t *Terminal) Changed() <-chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.changed
+}
+
+// LastChange returns when output last updated the screen (when the
+// terminal was created, if it has not yet).
+func (t *Terminal) LastChange() time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.lastChange
+}
+
+// Done returns a channel that is closed once the command's output has
+// ended (it exited, or closed the terminal), after which the screen no
+// longer changes.
+func (t *Terminal) Done() <-chan struct{} {
+	return t.done
+}
+
 // readLoop continuously reads from the PTY and updates the virtual terminal.
 // It intercepts terminal queries (DSR, DA1, etc.) and responds appropriately
 // so that TUI applications like Bubble Tea can render properly.
@@ -121,6 +245,7 @@ func (t *Terminal) readLoop() {
 
 		if n > 0 {
 			data := buf[:n]
+			t.trace.data(TraceRead, data)
 
 			// Scan for and respond to terminal queries before passing to vt10x
 			data = t.handleTerminalQueries(data)
@@ -128,12 +253,28 @@ func (t *Terminal) readLoop() {
 			if len(data) > 0 {
 				t.mu.Lock()
 				_, _ = t.vt.Write(data)
+				close(t.changed)
+				t.changed = make(chan struct{})
+				t.lastChange = time.Now()
+				t.appendOutput(data)
+				if t.recordFrames {
+					t.recordFrame()
+				}
 				t.mu.Unlock()
 			}
 		}
 	}
 }
 
+// waitLoop reaps the command as soon as it exits and records its status.
+func (t *Terminal) waitLoop() {
+	err := t.cmd.Wait()
+	t.mu.Lock()
+	t.exitErr = err
+	t.mu.Unlock()
+	close(t.exited)
+}
+
 // handleTerminalQueries scans the output for terminal queries and responds to them.
 // It returns the data with query sequences removed (they shouldn't be rendered).
 func (t *Terminal) handleTerminalQueries(data []byte) []byte {
@@ -157,15 +298,20 @@ func (t *Terminal) handleTerminalQueries(data []byte) []byte {
 // tryHandleQuery checks if there's a terminal query at position i and handles it.
 // Returns the number of bytes to skip if a query was handled, 0 otherwise.
 func (t *Terminal) tryHandleQuery(data []byte, i int) int {
-	// CSI sequences: ESC [
-	if skip := t.tryHandleCSIQuery(data, i); skip > 0 {
-		return skip
+	skip := t.tryHandleCSIQuery(data, i)
+	if skip == 0 {
+		skip = t.tryHandleOSCQuery(data, i)
 	}
-	// OSC sequences: ESC ]
-	if skip := t.tryHandleOSCQuery(data, i); skip > 0 {
-		return skip
+	if skip > 0 {
+		t.trace.data(TraceQuery, data[i:i+skip])
 	}
-	return 0
+	// Replies are sent once the query is known so the trace shows them in
+	// order.
+	for _, response := range t.responses {
+		_, _ = responseWriter{t}.Write([]byte(response))
+	}
+	t.responses = t.responses[:0]
+	return skip
 }
 
 // tryHandleCSIQuery handles CSI (Control Sequence Introducer) queries.
@@ -282,6 +428,11 @@ func (t *Terminal) findOSCEnd(data []byte, offset int) int {
 	return -1
 }
 
+// respond queues a reply to an intercepted query (see tryHandleQuery).
+func (t *Terminal) respond(response string) {
+	t.responses = append(t.responses, response)
+}
+
 // respondToDA1 sends primary device attributes response.
 // This tells the application we're a VT220-compatible terminal.
 // Response: ESC [ ? 6 2 ; 4 c (VT220 with sixel - even though we don't render it)
@@ -289,7 +440,7 @@ func (t *Terminal) respondToDA1() {
 	// VT220 response with common capabilities
 	// 62 = VT220, 4 = sixel (claim support for better compat)
 	response := "\x1b[?62;4c"
-	_, _ = t.ptyFile.WriteString(response)
+	t.respond(response)
 }
 
 // respondToDA2 sends secondary device attributes response.
@@ -298,7 +449,7 @@ func (t *Terminal) respondToDA1() {
 func (t *Terminal) respondToDA2() {
 	// Identify as VT220, version 0
 	response := "\x1b[>1;0;0c"
-	_, _ = t.ptyFile.WriteString(response)
+	t.respond(response)
 }
 
 // respondToWindowSizePixels responds to XTWINOPS 14 (window size in pixels).
@@ -313,7 +464,7 @@ func (t *Terminal) respondToWindowSizePixels() {
 	height := rows * 16
 	width := cols * 8
 	response := fmt.Sprintf("\x1b[4;%d;%dt", height, width)
-	_, _ = t.ptyFile.WriteString(response)
+	t.respond(response)
 }
 
 // respondToTextAreaSize responds to XTWINOPS 18 (text area size in chars).
@@ -325,7 +476,7 @@ func (t *Terminal) respondToTextAreaSize() {
 	t.mu.Unlock()
 
 	response := fmt.Sprintf("\x1b[8;%d;%dt", rows, cols)
-	_, _ = t.ptyFile.WriteString(response)
+	t.respond(response)
 }
 
 // respondToScreenSize responds to XTWINOPS 19 (screen size in chars).
@@ -337,7 +488,7 @@ func (t *Terminal) respondToScreenSize() {
 	t.mu.Unlock()
 
 	response := fmt.Sprintf("\x1b[9;%d;%dt", rows, cols)
-	_, _ = t.ptyFile.WriteString(response)
+	t.respond(response)
 }
 
 // respondToBackgroundColorQuery sends a response for OSC 11 query.
@@ -345,7 +496,7 @@ func (t *Terminal) respondToScreenSize() {
 func (t *Terminal) respondToBackgroundColorQuery() {
 	// Return black background (common default)
 	response := "\x1b]11;rgb:0000/0000/0000\x1b\\"
-	_, _ = t.ptyFile.WriteString(response)
+	t.respond(response)
 }
 
 // respondToForegroundColorQuery sends a response for OSC 10 query.
@@ -353,7 +504,7 @@ func (t *Terminal) respondToBackgroundColorQuery() {
 func (t *Terminal) respondToForegroundColorQuery() {
 	// Return white foreground (common default)
 	response := "\x1b]10;rgb:ffff/ffff/ffff\x1b\\"
-	_, _ = t.ptyFile.WriteString(response)
+	t.respond(response)
 }
 
 // Screenshot captures the current terminal screen as a text grid.
@@ -380,6 +531,7 @@ func (t *Terminal) ScreenshotWithCursor() (screen string, cursorCol, cursorRow i
 
 // SendKeys sends keystrokes to the running application.
 func (t *Terminal) SendKeys(keys string) error {
+	t.trace.data(TraceKeys, []byte(keys))
 	_, err := t.ptyFile.WriteString(keys)
 	return err
 }
@@ -392,55 +544,131 @@ func (t *Terminal) SendKey(key Key) error {
 // Wait waits for the command to exit.
 func (t *Terminal) Wait() error {
 	<-t.done
-	return t.cmd.Wait()
+	<-t.exited
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.exitErr
+}
+
+// ExitStatus returns the command's exit code and whether it has exited.
+// A command killed by a signal reports -1.
+func (t *Terminal) ExitStatus() (code int, exited bool) {
+	select {
+	case <-t.exited:
+	default:
+		return 0, false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return exitCode(t.exitErr), true
+}
+
+// WaitExit waits up to timeout for the command to exit and returns its
+// exit code.
+func (t *Terminal) WaitExit(timeout time.Duration) (int, error) {
+	done := t.trace.wait(WaitExit, "")
+	select {
+	case <-t.exited:
+		done(ReasonExited)
+	case <-time.After(timeout):
+		done(ReasonTimeout)
+		return 0, fmt.Errorf("timeout waiting for command to exit")
+	}
+	code, _ := t.ExitStatus()
+	return code, nil
+}
+
+// exitCode converts the error returned by exec.Cmd.Wait into an exit code.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
 }
 
 // WaitForStable waits until the screen content stabilizes (no changes for duration).
 func (t *Terminal) WaitForStable(timeout, stableDuration time.Duration) error {
+	done := t.trace.wait(WaitStable, "")
 	deadline := time.Now().Add(timeout)
 	lastScreen := ""
 	stableSince := time.Time{}
 
 	for time.Now().Before(deadline) {
-		screen := t.Screenshot()
+		screen := t.stableScreen()
 
 		if screen != lastScreen {
 			lastScreen = screen
 			stableSince = time.Now()
 		} else if !stableSince.IsZero() && time.Since(stableSince) >= stableDuration {
+			done(ReasonStable)
 			return nil
 		}
 
 		time.Sleep(50 * time.Millisecond)
 	}
 
+	done(ReasonTimeout)
 	return fmt.Errorf("timeout waiting for stable screen")
 }
 
+// stableScreen returns the screen as compared by WaitForStable.
+func (t *Terminal) stableScreen() string {
+	if t.stableMask.Empty() {
+		return t.Screenshot()
+	}
+	return t.stableMask.Apply(t.Peek()).String()
+}
+
 // WaitForText waits until the specified text appears on screen.
 func (t *Terminal) WaitForText(text string, timeout time.Duration) error {
+	done := t.trace.wait(WaitText, text)
 	deadline := time.Now().Add(timeout)
 
 	for time.Now().Before(deadline) {
 		screen := t.Screenshot()
 		if containsText(screen, text) {
+			done(ReasonFound)
 			return nil
 		}
 		time.Sleep(50 * time.Millisecond)
 	}
 
+	done(ReasonTimeout)
 	return fmt.Errorf("timeout waiting for text: %q", text)
 }
 
+// Delay pauses for d, or until the command's output ends. Unlike
+// time.Sleep, the pause shows up in the trace.
+func (t *Terminal) Delay(d time.Duration) {
+	done := t.trace.wait(WaitDelay, "")
+	select {
+	case <-time.After(d):
+		done(ReasonElapsed)
+	case <-t.done:
+		done(ReasonExited)
+	}
+}
+
 // Close terminates the command and cleans up resources.
 func (t *Terminal) Close() error {
-	if t.cmd.Process != nil {
-		_ = t.cmd.Process.Kill()
-	}
+	// Kill the whole process group, and anything else in the command's
+	// session, so children of the command do not outlive it
+	_ = t.signalGroup(syscall.SIGKILL)
+	t.killLeftovers(nil, 0)
 	if t.ptyFile != nil {
 		_ = t.ptyFile.Close()
 	}
-	<-t.done
+	// A process we could not kill may hold the PTY open, so do not wait
+	// for the reader forever
+	select {
+	case <-t.done:
+	case <-time.After(closeTimeout):
+	}
 	return nil
 }
 
@@ -468,8 +696,9 @@ func (t *Terminal) Resize(cols, rows int) error {
 	t.rows = rows
 	t.cols = cols
 
-	// Recreate virtual terminal with new size
-	t.vt = vt10x.New(vt10x.WithSize(cols, rows))
+	// Resize the emulator in place: a new one would lose the screen and the
+	// writer that answers the application's queries
+	t.vt.Resize(cols, rows)
 
 	return nil
 }
@@ -0,0 +1,110 @@
+package terminal
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// dsrScript prints "ready", waits for a line, then asks for the cursor
+// position (DSR) and prints the reply it reads back.
+const dsrScript = `stty raw -echo
+printf ready
+read -r line
+printf '\033[6n'
+reply=""
+while c=$(dd bs=1 count=1 2>/dev/null); [ -n "$c" ] && [ "$c" != R ]; do
+	reply="$reply$c"
+done
+printf '\r\nreply %s\r\n' "$(printf '%s' "$reply" | tr -d '\033')"
+read -r line
+`
+
+func TestResizeKeepsQueryReplies(t *testing.T) {
+	term, err := New("sh", []string{"-c", dsrScript}, DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer term.Close()
+
+	if err := term.WaitForText("ready", 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := term.Resize(100, 30); err != nil {
+		t.Fatal(err)
+	}
+	if cols, rows := term.Size(); cols != 100 || rows != 30 {
+		t.Fatalf("Size() = %d, %d, want 100, 30", cols, rows)
+	}
+	if !strings.Contains(term.Screenshot(), "ready") {
+		t.Errorf("screen was cleared by Resize:\n%s", term.Screenshot())
+	}
+
+	if err := term.SendKeys("\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := term.WaitForText("reply [1;6", 5*time.Second); err != nil {
+		t.Fatalf("no DSR reply after Resize: %v\n%s", err, term.Screenshot())
+	}
+	if err := term.SendKeys("\n"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// allocEnv makes the test binary, run as a command, allocate more memory
+// than the limit the test gives it.
+const allocEnv = "TUI_GOGGLES_TEST_ALLOC"
+
+func TestMain(m *testing.M) {
+	RunSandboxHelper()
+	if os.Getenv(allocEnv) != "" {
+		buf := make([]byte, 2<<30)
+		for i := range buf {
+			buf[i] = 1
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestRecordFrames(t *testing.T) {
+	script := `printf one; sleep 0.2; printf '\rtwo'; sleep 0.2; printf '\rtwo'; sleep 0.2`
+	term, err := New("sh", []string{"-c", script}, Options{RecordFrames: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer term.Close()
+	if _, err := term.WaitExit(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	frames := term.Frames()
+	var lines []string
+	for _, f := range frames {
+		lines = append(lines, strings.TrimSpace(strings.SplitN(f.Screen, "\n", 2)[0]))
+	}
+	if strings.Join(lines, ",") != "one,two" {
+		t.Fatalf("frames show %q, want one, two", lines)
+	}
+	if frames[1].Writes != 2 {
+		t.Errorf("second frame Writes = %d, want 2 (drawn twice)", frames[1].Writes)
+	}
+	if !frames[1].Time.After(frames[0].Time) {
+		t.Errorf("frame times out of order: %v, %v", frames[0].Time, frames[1].Time)
+	}
+}
+
+func TestFramesNotRecordedByDefault(t *testing.T) {
+	term, err := New("sh", []string{"-c", "echo hi"}, DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer term.Close()
+	if _, err := term.WaitExit(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if frames := term.Frames(); len(frames) != 0 {
+		t.Errorf("Frames() = %d frames without RecordFrames, want none", len(frames))
+	}
+}
@@ -0,0 +1,128 @@
+package terminal
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Trace event kinds, as written to Options.Trace.
+const (
+	// TraceRead is a chunk of output read from the PTY, before queries are
+	// stripped.
+	TraceRead = "read"
+	// TraceQuery is a terminal query intercepted from the output.
+	TraceQuery = "query"
+	// TraceResponse is a reply written back to the application, either to
+	// an intercepted query or by the emulator itself (e.g. cursor position).
+	TraceResponse = "response"
+	// TraceKeys is input sent to the application.
+	TraceKeys = "keys"
+	// TraceWaitStart and TraceWaitEnd bracket a wait; the end event says why
+	// it ended.
+	TraceWaitStart = "wait_start"
+	TraceWaitEnd   = "wait_end"
+	// TraceSnapshot is a capture of the screen grid.
+	TraceSnapshot = "snapshot"
+)
+
+// Wait kinds and the reasons they end, as reported in trace events.
+const (
+	WaitStable = "stable"
+	WaitText   = "text"
+	WaitExit   = "exit"
+	WaitDelay  = "delay"
+
+	ReasonStable  = "stable"
+	ReasonFound   = "found"
+	ReasonExited  = "exited"
+	ReasonElapsed = "elapsed"
+	ReasonTimeout = "timeout"
+)
+
+// TraceEvent is one line of the trace log.
+type TraceEvent struct {
+	Time      time.Time `json:"time"`
+	ElapsedMs float64   `json:"elapsed_ms"`
+	Event     string    `json:"event"`
+	// Data holds the bytes read, the query, the response or the keys. Data
+	// that is not valid UTF-8 is in DataBase64 instead.
+	Data       string  `json:"data,omitempty"`
+	DataBase64 string  `json:"data_base64,omitempty"`
+	Bytes      int     `json:"bytes,omitempty"`
+	Wait       string  `json:"wait,omitempty"`
+	Text       string  `json:"text,omitempty"`
+	Reason     string  `json:"reason,omitempty"`
+	DurationMs float64 `json:"duration_ms,omitempty"`
+}
+
+// tracer writes trace events as JSON lines. A nil tracer discards them.
+type tracer struct {
+	mu    sync.Mutex
+	enc   *json.Encoder
+	start time.Time
+}
+
+func newTracer(w io.Writer) *tracer {
+	if w == nil {
+		return nil
+	}
+	return &tracer{enc: json.NewEncoder(w), start: time.Now()}
+}
+
+func (tr *tracer) emit(ev TraceEvent) {
+	if tr == nil {
+		return
+	}
+	now := time.Now()
+	ev.Time = now
+	ev.ElapsedMs = millis(now.Sub(tr.start))
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	_ = tr.enc.Encode(ev)
+}
+
+// data emits an event carrying raw bytes.
+func (tr *tracer) data(event string, b []byte) {
+	if tr == nil {
+		return
+	}
+	ev := TraceEvent{Event: event, Bytes: len(b)}
+	if utf8.Valid(b) {
+		ev.Data = string(b)
+	} else {
+		ev.DataBase64 = base64.StdEncoding.EncodeToString(b)
+	}
+	tr.emit(ev)
+}
+
+// wait emits the start of a wait and returns a function that emits its end.
+func (tr *tracer) wait(kind, text string) func(reason string) {
+	if tr == nil {
+		return func(string) {}
+	}
+	start := time.Now()
+	tr.emit(TraceEvent{Event: TraceWaitStart, Wait: kind, Text: text})
+	return func(reason string) {
+		tr.emit(TraceEvent{Event: TraceWaitEnd, Wait: kind, Text: text, Reason: reason, DurationMs: millis(time.Since(start))})
+	}
+}
+
+// millis converts a duration to fractional milliseconds.
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// responseWriter writes replies to the application, tracing them.
+type responseWriter struct {
+	t *Terminal
+}
+
+func (w responseWriter) Write(p []byte) (int, error) {
+	w.t.trace.data(TraceResponse, p)
+	return w.t.ptyFile.Write(p)
+}
@@ -0,0 +1,132 @@
+package terminal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lockedBuffer is a bytes.Buffer safe to write from the terminal's
+// goroutines while the test reads it.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestTrace(t *testing.T) {
+	var trace lockedBuffer
+	opts := DefaultOptions()
+	opts.Trace = &trace
+	script := `stty raw -echo; printf 'hello\033[c'; read -r line; printf bye`
+	term, err := New("sh", []string{"-c", script}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := term.WaitForText("hello", 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := term.SendKeys("x\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := term.WaitForText("bye", 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := term.WaitExit(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	term.Close()
+
+	var events []TraceEvent
+	scanner := bufio.NewScanner(strings.NewReader(trace.String()))
+	for scanner.Scan() {
+		var ev TraceEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			t.Fatalf("trace line is not JSON: %v\n%s", err, scanner.Text())
+		}
+		if ev.Time.IsZero() {
+			t.Errorf("event without a time: %s", scanner.Text())
+		}
+		events = append(events, ev)
+	}
+
+	var read strings.Builder
+	for _, ev := range events {
+		if ev.Event == TraceRead {
+			read.WriteString(ev.Data)
+		}
+	}
+	if !strings.Contains(read.String(), "hello\x1b[c") || !strings.Contains(read.String(), "bye") {
+		t.Errorf("read events hold %q, want all the output", read.String())
+	}
+
+	// Each sequence must appear in this order, though other events may come
+	// in between
+	sequences := [][]TraceEvent{
+		{
+			{Event: TraceQuery, Data: "\x1b[c"},
+			{Event: TraceResponse},
+		},
+		{
+			{Event: TraceWaitStart, Wait: WaitText, Text: "hello"},
+			{Event: TraceWaitEnd, Wait: WaitText, Text: "hello", Reason: ReasonFound},
+			{Event: TraceKeys, Data: "x\n"},
+			{Event: TraceWaitStart, Wait: WaitText, Text: "bye"},
+			{Event: TraceWaitEnd, Wait: WaitText, Text: "bye", Reason: ReasonFound},
+			{Event: TraceWaitStart, Wait: WaitExit},
+			{Event: TraceWaitEnd, Wait: WaitExit, Reason: ReasonExited},
+		},
+	}
+	for _, seq := range sequences {
+		next := 0
+		for _, ev := range events {
+			if next < len(seq) && traceMatches(ev, seq[next]) {
+				next++
+			}
+		}
+		if next < len(seq) {
+			t.Errorf("trace lacks %+v (after %d of the sequence)\n%s", seq[next], next, trace.String())
+		}
+	}
+}
+
+// traceMatches reports whether ev matches the fields set in want.
+func traceMatches(ev, want TraceEvent) bool {
+	return ev.Event == want.Event &&
+		(want.Data == "" || ev.Data == want.Data) &&
+		(want.Wait == "" || ev.Wait == want.Wait) &&
+		(want.Text == "" || ev.Text == want.Text) &&
+		(want.Reason == "" || ev.Reason == want.Reason)
+}
+
+func TestTraceBinaryData(t *testing.T) {
+	var buf bytes.Buffer
+	tr := newTracer(&buf)
+	tr.data(TraceRead, []byte{0xff, 'a'})
+	var ev TraceEvent
+	if err := json.Unmarshal(buf.Bytes(), &ev); err != nil {
+		t.Fatal(err)
+	}
+	if ev.Data != "" || ev.DataBase64 != "/2E=" || ev.Bytes != 2 {
+		t.Errorf("event = %+v, want the bytes in data_base64", ev)
+	}
+
+	var none *tracer
+	none.data(TraceRead, []byte("x"))
+	none.wait(WaitStable, "")(ReasonStable)
+}